@@ -0,0 +1,85 @@
+package fuzzymatcher
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sync"
+
+	fmcore "github.com/oiamo123/fuzzy_matcher/fuzzy_matcher_core"
+	ft "github.com/oiamo123/fuzzy_matcher/fuzzy_types"
+)
+
+/*
+BATCH SEARCH FLOW
+1. Resolve the number of workers
+2. Start the workers, each one searches the entries it takes from the queue
+	- Each search cleans expired entries itself, that only takes the write lock when something has expired since the last search
+3. Queue the index of every entry, stopping early if ctx is done
+4. Results are written to the entry's index so the output lines up with the input
+*/
+
+// Searches each entry on a bounded pool of workers, results[i] is the result for entries[i]
+// Only the first set of options is used
+func (fuzzyMatcher *FuzzyMatcher[T]) SearchBatch(entries []T, opts ...ft.BatchOptions) []ft.BatchResult[T] {
+	return fuzzyMatcher.SearchBatchContext(context.Background(), entries, opts...)
+}
+
+// Searches each entry on a bounded pool of workers until ctx is done
+// Entries that weren't searched before ctx was done report an error wrapping ErrSearchTruncated and ctx's error
+func (fuzzyMatcher *FuzzyMatcher[T]) SearchBatchContext(ctx context.Context, entries []T, opts ...ft.BatchOptions) []ft.BatchResult[T] {
+	options := ft.BatchOptions{}
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+
+	results := make([]ft.BatchResult[T], len(entries))
+	if len(entries) == 0 {
+		return results
+	}
+
+	// 1.
+	workers := options.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if workers > len(entries) {
+		workers = len(entries)
+	}
+
+	// 2.
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			// 4.
+			for i := range indexes {
+				found, matches, err := fuzzyMatcher.FuzzyMatcherCore.SearchContext(ctx, entries[i], options.SearchOptions)
+				results[i] = ft.BatchResult[T]{Found: found, Matches: matches, Err: err}
+			}
+		}()
+	}
+
+	// 3.
+	next := 0
+queue:
+	for ; next < len(entries); next++ {
+		select {
+		case indexes <- next:
+		case <-ctx.Done():
+			break queue
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	// Entries that were never queued fail the same way a cancelled search does
+	for i := next; i < len(entries); i++ {
+		results[i] = ft.BatchResult[T]{Err: fmt.Errorf("%w: %w", fmcore.ErrSearchTruncated, ctx.Err())}
+	}
+
+	return results
+}
@@ -1,6 +1,8 @@
 package fuzzymatcher
 
 import (
+	"context"
+
 	fmcore "github.com/oiamo123/fuzzy_matcher/fuzzy_matcher_core"
 	ft "github.com/oiamo123/fuzzy_matcher/fuzzy_types"
 )
@@ -26,11 +28,32 @@ func (fuzzyMatcher *FuzzyMatcher[T]) InsertEntries(entries []T) {
 	fuzzyMatcher.FuzzyMatcherCore.Build(entries)
 }
 
-func (fuzzyMatcher *FuzzyMatcher[T]) Search(entry T) (bool, []ft.FuzzyMatch[T]) {
+// Inserts an array of entries, replacing the stored entries with the same IDs
+func (fuzzyMatcher *FuzzyMatcher[T]) UpsertEntries(entries []T) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	return fuzzyMatcher.FuzzyMatcherCore.Upsert(entries)
+}
+
+// Searches the fuzzy matcher, opts can be used to page through results or set a minimum score
+func (fuzzyMatcher *FuzzyMatcher[T]) Search(entry T, opts ...ft.SearchOptions) (bool, []ft.FuzzyMatch[T]) {
 	fuzzyMatcher.FuzzyMatcherCore.Clean()
-	return fuzzyMatcher.FuzzyMatcherCore.SearchFuzzy(entry)
+	return fuzzyMatcher.FuzzyMatcherCore.SearchFuzzy(entry, opts...)
+}
+
+// Searches the fuzzy matcher until ctx is done, returning any matches found so far with an error if it was cut short
+func (fuzzyMatcher *FuzzyMatcher[T]) SearchContext(ctx context.Context, entry T, opts ...ft.SearchOptions) (bool, []ft.FuzzyMatch[T], error) {
+	fuzzyMatcher.FuzzyMatcherCore.Clean()
+	return fuzzyMatcher.FuzzyMatcherCore.SearchContext(ctx, entry, opts...)
 }
 
 func (fuzzyMatcher *FuzzyMatcher[T]) RemoveEntries(entries []T) {
 	fuzzyMatcher.FuzzyMatcherCore.RemoveEntries(entries)
 }
+
+// Removes entries by ID, the original entries aren't needed
+func (fuzzyMatcher *FuzzyMatcher[T]) RemoveByID(ids ...int) {
+	fuzzyMatcher.FuzzyMatcherCore.RemoveByID(ids...)
+}
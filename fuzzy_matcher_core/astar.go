@@ -0,0 +1,226 @@
+package fuzzymatchercore
+
+import (
+	"container/heap"
+
+	ft "github.com/oiamo123/fuzzy_matcher/fuzzy_types"
+)
+
+// aStarState is a place in the trie paired with how much of the search word has been used to get there
+type aStarState struct {
+	node       *ft.FuzzyMatcherNode
+	index      int     // Characters of the search word used
+	edits      float64 // Cost of the edits so far
+	estimate   float64 // edits plus the least the rest of the way to a match can cost
+	added      []rune  // Trie characters taken by the step to this state
+	edit       *ft.Edit
+	extensions int // Characters added past the end of the search word
+	parent     *aStarState
+}
+
+// aStarSearch holds the state of one A* search over a field's values
+type aStarSearch struct {
+	word     []rune // The value being searched for, without the key
+	prefix   string // "field:"
+	maxEdits int
+	cheapest float64 // Least any edit can cost
+	explain  bool
+	queue    *aStarHeap
+	best     map[ft.ExploredState]float64 // Cheapest edits each state has been queued with
+}
+
+/*
+A* SEARCH FLOW
+1. Follow the field's key down the trie, only the value is fuzzy matched
+2. Queue the value's first node, states are ordered by their edits plus a lower bound on the edits left
+	- If the rest of the search word can't be followed from a state, at least one more edit is needed
+	- The bound never overestimates, so states come off the queue cheapest first
+3. Take the state with the lowest estimate off the queue, skipping states already reached more cheaply
+4. If the whole search word has been used on an end of string node it's a match
+	- Matches come off the queue cheapest first, so the search stops once it has found MaxMatches
+5. Queue the states one step away, the same steps Recurse takes
+	- Matching characters, skips, substitutions, insertions, swaps and OCR misreads use up the search word
+	- Characters past the end of the search word are free, like the breadth first search
+	- States that can't stay within MaxEdits aren't queued
+*/
+
+// Searches the trie for a field value cheapest first, the caller must hold the lock
+// Takes the same parameters as Recurse, MaxDepth isn't used as every edit is counted against MaxEdits
+func (fmc *FuzzyMatcherCore[T]) SearchAStar(params ft.RecurseParameters) []ft.MatchCandidate {
+	// 1.
+	root := fmc.valueRoot(params.Key)
+	if root == nil {
+		return nil
+	}
+
+	search := &aStarSearch{
+		word:     params.Word[len(params.Key)+1:],
+		prefix:   string(params.Key) + ":",
+		maxEdits: params.MaxEdits,
+		cheapest: fmc.CoreParams.EditCosts.Cheapest(),
+		explain:  params.Explain,
+		queue:    &aStarHeap{},
+		best:     make(map[ft.ExploredState]float64),
+	}
+
+	// 2.
+	fmc.queueAStar(search, &aStarState{node: root}, nil)
+
+	matches := []ft.MatchCandidate{}
+	closed := make(map[ft.ExploredState]bool)
+	for search.queue.Len() > 0 {
+		// 3.
+		state := heap.Pop(search.queue).(*aStarState)
+		key := ft.ExploredState{Position: state.node.Position(), Index: state.index}
+		if closed[key] {
+			continue
+		}
+		closed[key] = true
+
+		if !params.Budget.Visit() {
+			break
+		}
+
+		// 4.
+		if state.index == len(search.word) && state.node.IsEndofString {
+			matches = append(matches, search.candidate(state))
+			if params.MaxMatches > 0 && len(matches) >= params.MaxMatches {
+				break
+			}
+		}
+
+		// 5.
+		fmc.expandAStar(search, state)
+	}
+
+	return matches
+}
+
+// Queues the states one step away from state
+func (fmc *FuzzyMatcherCore[T]) expandAStar(search *aStarSearch, state *aStarState) {
+	word, node, i := search.word, state.node, state.index
+
+	// Past the end of the search word only extensions are left
+	if i == len(word) {
+		node.EachChild(func(child *ft.FuzzyMatcherNode) bool {
+			next := &aStarState{node: child, index: i, edits: state.edits, added: []rune{child.Char}, extensions: state.extensions + 1}
+			if search.explain {
+				next.edit = &ft.Edit{Operation: ft.EditExtension, Position: i + state.extensions, To: string(child.Char)}
+			}
+			fmc.queueAStar(search, next, state)
+			return true
+		})
+		return
+	}
+
+	char := word[i]
+	step := func(node *ft.FuzzyMatcherNode, index int, added []rune, operation ft.EditOperation, from, to string) {
+		next := &aStarState{node: node, index: index, edits: state.edits, added: added}
+		if operation != "" {
+			next.edits += fmc.editCost(operation, from, to)
+			if search.explain {
+				next.edit = &ft.Edit{Operation: operation, Position: i, From: from, To: to}
+			}
+		}
+		fmc.queueAStar(search, next, state)
+	}
+
+	if next := node.Child(char); next != nil {
+		step(next, i+1, []rune{char}, "", "", "")
+	}
+
+	step(node, i+1, nil, ft.EditSkip, string(char), "")
+
+	node.EachChild(func(child *ft.FuzzyMatcherNode) bool {
+		if child.Char != char {
+			step(child, i+1, []rune{child.Char}, ft.EditSubstitution, string(char), string(child.Char))
+		}
+		step(child, i, []rune{child.Char}, ft.EditInsertion, "", string(child.Char))
+		return true
+	})
+
+	if i+1 < len(word) && word[i+1] != char {
+		if first := node.Child(word[i+1]); first != nil {
+			if second := first.Child(char); second != nil {
+				swapped := []rune{word[i+1], char}
+				step(second, i+2, swapped, ft.EditTransposition, string(word[i:i+2]), string(swapped))
+			}
+		}
+	}
+
+	if !fmc.CoreParams.CorrectOcrMisreads {
+		return
+	}
+
+	for _, confusion := range fmc.ocrConfusions[char] {
+		if !confusion.matches(word, i) {
+			continue
+		}
+
+		target := node
+		for _, r := range confusion.to {
+			if target = target.Child(r); target == nil {
+				break
+			}
+		}
+		if target != nil {
+			step(target, i+len(confusion.from), confusion.to, confusion.operation(), string(confusion.from), string(confusion.to))
+		}
+	}
+}
+
+// Queues a state if it can stay within MaxEdits and hasn't been queued as cheaply
+func (fmc *FuzzyMatcherCore[T]) queueAStar(search *aStarSearch, state, parent *aStarState) {
+	state.estimate = state.edits + search.heuristic(state.node, state.index)
+	if ft.ExceedsEdits(state.estimate, search.maxEdits) {
+		return
+	}
+
+	key := ft.ExploredState{Position: state.node.Position(), Index: state.index}
+	if best, ok := search.best[key]; ok && best <= state.edits {
+		return
+	}
+	search.best[key] = state.edits
+
+	state.parent = parent
+	heap.Push(search.queue, state)
+}
+
+// Returns a lower bound on the edits left, nothing if the rest of the word can be followed from node and the cheapest edit otherwise
+func (s *aStarSearch) heuristic(node *ft.FuzzyMatcherNode, index int) float64 {
+	for _, r := range s.word[index:] {
+		if node = node.Child(r); node == nil {
+			return s.cheapest
+		}
+	}
+
+	return 0
+}
+
+// Builds the match for a state by walking back to the first one
+func (s *aStarSearch) candidate(state *aStarState) ft.MatchCandidate {
+	steps := []*aStarState{}
+	for current := state; current != nil; current = current.parent {
+		steps = append(steps, current)
+	}
+
+	path := []rune(s.prefix)
+	var edits []ft.Edit
+	if s.explain {
+		edits = []ft.Edit{}
+	}
+
+	for i := len(steps) - 1; i >= 0; i-- {
+		path = append(path, steps[i].added...)
+		if steps[i].edit != nil {
+			edits = append(edits, *steps[i].edit)
+		}
+	}
+
+	return ft.MatchCandidate{
+		Text:      string(path),
+		EditCount: state.edits,
+		ID:        state.node.IDs(),
+		Edits:     edits,
+	}
+}
@@ -0,0 +1,31 @@
+package fuzzymatchercore
+
+type aStarHeap []*aStarState
+
+// Heap interface implementation for the A* search (lowest estimate first)
+// Ties go to the state with more edits behind it, it's closer to a match
+func (h aStarHeap) Len() int { return len(h) }
+func (h aStarHeap) Less(i, j int) bool {
+	if h[i].estimate != h[j].estimate {
+		return h[i].estimate < h[j].estimate
+	}
+	if h[i].edits != h[j].edits {
+		return h[i].edits > h[j].edits
+	}
+	return h[i].index > h[j].index
+}
+func (h aStarHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+// Adds an element to the heap, maintaining the heap property
+func (h *aStarHeap) Push(x interface{}) {
+	*h = append(*h, x.(*aStarState))
+}
+
+// Removes the state with the lowest estimate from the heap
+func (h *aStarHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[0 : n-1]
+	return x
+}
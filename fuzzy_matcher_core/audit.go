@@ -0,0 +1,168 @@
+package fuzzymatchercore
+
+import (
+	ft "github.com/oiamo123/fuzzy_matcher/fuzzy_types"
+)
+
+/*
+AUDIT FLOW
+1. Walk the trie depth first, checking each node's IDs and flags
+2. Compare each node's count with the number of IDs at or below it
+3. Compare the reverse index and the entries with the IDs found in the trie
+4. If repairing:
+	4.1. Remove orphaned IDs from the trie and the field backends, and fix end of string flags
+	4.2. Prune dangling nodes
+	4.3. Recount every node
+	4.4. Rebuild the reverse index and drop entries without nodes
+*/
+
+// Walks the trie and reports inconsistencies in counts, IDs and end of string flags
+// When repair is true the inconsistencies are fixed and the write lock is held for the walk
+func (fmc *FuzzyMatcherCore[T]) Audit(repair bool) ft.AuditReport {
+	mu := fmc.lock()
+	if repair {
+		mu.Lock()
+		defer mu.Unlock()
+	} else {
+		mu.RLock()
+		defer mu.RUnlock()
+	}
+
+	report := ft.AuditReport{}
+	if fmc.Root == nil {
+		return report
+	}
+
+	// 1. & 2.
+	nodes := []*ft.FuzzyMatcherNode{}
+	var walk func(node *ft.FuzzyMatcherNode) int
+	walk = func(node *ft.FuzzyMatcherNode) int {
+		report.Nodes++
+		nodes = append(nodes, node)
+
+		for _, id := range node.IDs() {
+			if _, ok := fmc.Entries[id]; !ok {
+				report.OrphanedIDs++
+			}
+		}
+
+		if node.IsEndofString && node.NumIDs() == 0 {
+			report.EndOfStringWithoutIDs++
+		}
+
+		if !node.IsEndofString && node.NumIDs() > 0 {
+			report.IDsWithoutEndOfString++
+		}
+
+		if node.Parent != nil && node.NumChildren() == 0 && node.NumIDs() == 0 {
+			report.DanglingNodes++
+		}
+
+		count := node.NumIDs()
+		node.EachEdge(func(child *ft.FuzzyMatcherNode) {
+			count += walk(child)
+		})
+
+		if node.Parent != nil && node.Count != count {
+			report.CountMismatches++
+		}
+
+		return count
+	}
+	walk(fmc.Root)
+
+	// 3.
+	trieIndex := buildIDIndex(nodes)
+	for id, indexed := range fmc.idNodes {
+		if !sameNodes(indexed, trieIndex[id]) {
+			report.IndexMismatches++
+		}
+	}
+	for id := range trieIndex {
+		if _, ok := fmc.idNodes[id]; !ok {
+			report.IndexMismatches++
+		}
+	}
+
+	for id := range fmc.Entries {
+		if _, ok := trieIndex[id]; !ok {
+			report.EntriesWithoutNodes++
+		}
+	}
+
+	if !repair || report.Consistent() {
+		return report
+	}
+
+	// 4.1.
+	for _, node := range nodes {
+		for _, id := range node.IDs() {
+			if _, ok := fmc.Entries[id]; !ok {
+				node.RemoveID(id)
+				fmc.removeFromBackend(node, id)
+			}
+		}
+		node.IsEndofString = node.NumIDs() > 0
+	}
+
+	// 4.2.
+	for _, node := range nodes {
+		fmc.Prune(node)
+	}
+
+	// 4.3.
+	var recount func(node *ft.FuzzyMatcherNode) int
+	recount = func(node *ft.FuzzyMatcherNode) int {
+		count := node.NumIDs()
+		node.EachEdge(func(child *ft.FuzzyMatcherNode) {
+			count += recount(child)
+		})
+
+		if node.Parent != nil {
+			node.Count = count
+		}
+
+		return count
+	}
+	recount(fmc.Root)
+
+	// 4.4.
+	nodes = nodes[:0]
+	var collect func(node *ft.FuzzyMatcherNode)
+	collect = func(node *ft.FuzzyMatcherNode) {
+		nodes = append(nodes, node)
+		node.EachEdge(collect)
+	}
+	collect(fmc.Root)
+	fmc.idNodes = buildIDIndex(nodes)
+
+	for id := range fmc.Entries {
+		if _, ok := fmc.idNodes[id]; !ok {
+			delete(fmc.Entries, id)
+			delete(fmc.expiries, id)
+		}
+	}
+
+	report.Repaired = true
+	return report
+}
+
+// Returns true if both lists hold the same nodes, in any order
+func sameNodes(a, b []*ft.FuzzyMatcherNode) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	seen := make(map[*ft.FuzzyMatcherNode]int, len(a))
+	for _, node := range a {
+		seen[node]++
+	}
+	for _, node := range b {
+		seen[node]--
+		if seen[node] < 0 {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,315 @@
+package fuzzymatchercore
+
+import (
+	"fmt"
+
+	ft "github.com/oiamo123/fuzzy_matcher/fuzzy_types"
+)
+
+// automatonSearch holds the state of one automaton search over a field's values
+type automatonSearch struct {
+	word     []rune // The value being searched for, without the key
+	prefix   string // "field:"
+	maxEdits int
+	path     []rune           // Characters from the key's node to the current node
+	rows     [][]float64      // rows[d][i] is the cheapest way to turn word[:i] into path[:d]
+	ocr      [][]ocrConfusion // OCR confusions in the word by where they end, nil when misreads aren't corrected
+	budget   *ft.SearchBudget
+	explain  bool
+	matches  []ft.MatchCandidate
+}
+
+// Searches for a field value with the field's backend, or the trie with the core's search engine, the caller must hold the lock
+func (fmc *FuzzyMatcherCore[T]) searchValue(params ft.RecurseParameters) []ft.MatchCandidate {
+	if backend, ok := fmc.backends[ft.Field(params.Key)]; ok {
+		return backend.Search(params)
+	}
+
+	switch fmc.CoreParams.SearchEngine {
+	case ft.AutomatonEngine:
+		return fmc.SearchAutomaton(params)
+	case ft.AStarEngine:
+		return fmc.SearchAStar(params)
+	}
+
+	return fmc.Recurse(params)
+}
+
+// Returns an error if the core is configured with a search engine that doesn't exist
+func (fmc *FuzzyMatcherCore[T]) validateSearchEngine() error {
+	switch fmc.CoreParams.SearchEngine {
+	case ft.RecursiveEngine, ft.AutomatonEngine, ft.AStarEngine:
+		return nil
+	}
+
+	return fmt.Errorf("unknown search engine %q", fmc.CoreParams.SearchEngine)
+}
+
+/*
+AUTOMATON SEARCH FLOW
+1. Follow the field's key down the trie, only the value is fuzzy matched
+2. Find the OCR misreads in the search word once, by where they end
+3. Walk the value's subtree depth first, keeping a row of edit costs for each depth
+	- rows[d][i] is the cheapest way to turn the first i characters of the word into the d characters of the path
+	- This runs the word's Levenshtein automaton over every path in the trie at once
+4. Fill in each node's row from the rows above it
+	- Skips, insertions and substitutions look at the row above, swaps and OCR misreads reach back further
+5. End of string nodes within the budget are matches, each is found once at its cheapest
+6. Stop going deeper once every cost in the row is over the budget, nothing below can get cheaper
+*/
+
+// Searches the trie for a field value with a Levenshtein automaton, the caller must hold the lock
+// Takes the same parameters as Recurse, MaxDepth isn't used as every edit is counted against MaxEdits
+func (fmc *FuzzyMatcherCore[T]) SearchAutomaton(params ft.RecurseParameters) []ft.MatchCandidate {
+	// 1.
+	node := fmc.valueRoot(params.Key)
+	if node == nil {
+		return nil
+	}
+
+	word := params.Word[len(params.Key)+1:]
+	search := &automatonSearch{
+		word:     word,
+		prefix:   string(params.Key) + ":",
+		maxEdits: params.MaxEdits,
+		budget:   params.Budget,
+		explain:  params.Explain,
+	}
+
+	// 2.
+	if fmc.CoreParams.CorrectOcrMisreads {
+		search.ocr = make([][]ocrConfusion, len(word)+1)
+		for start, r := range word {
+			for _, confusion := range fmc.ocrConfusions[r] {
+				if confusion.matches(word, start) {
+					end := start + len(confusion.from)
+					search.ocr[end] = append(search.ocr[end], confusion)
+				}
+			}
+		}
+	}
+
+	// The empty path is reached by skipping the word, or misreading it as nothing
+	first := make([]float64, len(word)+1)
+	for i := 1; i <= len(word); i++ {
+		first[i] = fmc.automatonOcrCost(search, first, i, first[i-1]+fmc.editCost(ft.EditSkip, string(word[i-1]), ""))
+	}
+	search.rows = append(search.rows, first)
+
+	// 3.
+	fmc.walkAutomaton(search, node)
+
+	return search.matches
+}
+
+// Visits a node whose row has been filled in, then its children
+func (fmc *FuzzyMatcherCore[T]) walkAutomaton(search *automatonSearch, node *ft.FuzzyMatcherNode) bool {
+	if !search.budget.Visit() {
+		return false
+	}
+
+	row := search.rows[len(search.rows)-1]
+
+	// 5.
+	if cost := row[len(search.word)]; node.IsEndofString && !ft.ExceedsEdits(cost, search.maxEdits) {
+		candidate := ft.MatchCandidate{
+			Text:      search.prefix + string(search.path),
+			EditCount: cost,
+			ID:        node.IDs(),
+		}
+		if search.explain {
+			candidate.Edits = fmc.automatonEdits(search)
+		}
+		search.matches = append(search.matches, candidate)
+	}
+
+	// 6.
+	cheapest := row[0]
+	for _, cost := range row[1:] {
+		cheapest = min(cheapest, cost)
+	}
+	if ft.ExceedsEdits(cheapest, search.maxEdits) {
+		return true
+	}
+
+	keepGoing := true
+	node.EachChild(func(child *ft.FuzzyMatcherNode) bool {
+		search.path = append(search.path, child.Char)
+		search.rows = append(search.rows, fmc.automatonRow(search))
+
+		keepGoing = fmc.walkAutomaton(search, child)
+
+		search.path = search.path[:len(search.path)-1]
+		search.rows = search.rows[:len(search.rows)-1]
+		return keepGoing
+	})
+
+	return keepGoing
+}
+
+// Fills in the row for the last character of the path
+func (fmc *FuzzyMatcherCore[T]) automatonRow(search *automatonSearch) []float64 {
+	// 4.
+	word, path, rows := search.word, search.path, search.rows
+	d := len(path)
+	char := path[d-1]
+	above := rows[d-1]
+
+	row := make([]float64, len(word)+1)
+	row[0] = above[0] + fmc.editCost(ft.EditInsertion, "", string(char))
+
+	for i := 1; i <= len(word); i++ {
+		w := word[i-1]
+
+		substitution := above[i-1]
+		if w != char {
+			substitution += fmc.editCost(ft.EditSubstitution, string(w), string(char))
+		}
+
+		cost := min(
+			substitution,
+			row[i-1]+fmc.editCost(ft.EditSkip, string(w), ""),
+			above[i]+fmc.editCost(ft.EditInsertion, "", string(char)),
+		)
+
+		if i >= 2 && d >= 2 && w != word[i-2] && w == path[d-2] && word[i-2] == char {
+			cost = min(cost, rows[d-2][i-2]+fmc.editCost(ft.EditTransposition, string(word[i-2:i]), string(path[d-2:d])))
+		}
+
+		row[i] = fmc.automatonOcrCost(search, row, i, cost)
+	}
+
+	return row
+}
+
+// Returns the cheaper of cost and the OCR misreads ending at i in the word and at the end of the path
+// row is the row being filled in, misreads that read as nothing stay on it
+func (fmc *FuzzyMatcherCore[T]) automatonOcrCost(search *automatonSearch, row []float64, i int, cost float64) float64 {
+	if search.ocr == nil {
+		return cost
+	}
+
+	d := len(search.path)
+	for _, confusion := range search.ocr[i] {
+		if !pathEndsWith(search.path, confusion.to) {
+			continue
+		}
+
+		previous := row
+		if len(confusion.to) > 0 {
+			previous = search.rows[d-len(confusion.to)]
+		}
+		cost = min(cost, previous[i-len(confusion.from)]+fmc.editCost(confusion.operation(), string(confusion.from), string(confusion.to)))
+	}
+
+	return cost
+}
+
+// Returns the node a field's values start from, nil if nothing has been inserted for the field
+func (fmc *FuzzyMatcherCore[T]) valueRoot(key []rune) *ft.FuzzyMatcherNode {
+	node := fmc.Root
+	for _, r := range key {
+		if node = node.Child(r); node == nil {
+			return nil
+		}
+	}
+
+	return node.Child(':')
+}
+
+// Returns true if path ends with suffix
+func pathEndsWith(path, suffix []rune) bool {
+	if len(suffix) > len(path) {
+		return false
+	}
+
+	for i, r := range suffix {
+		if path[len(path)-len(suffix)+i] != r {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Walks back through the rows from the end of the word to find the edits taken on the cheapest path
+// Positions are in the search word, the same as the recursive engine's
+func (fmc *FuzzyMatcherCore[T]) automatonEdits(search *automatonSearch) []ft.Edit {
+	word, path, rows := search.word, search.path, search.rows
+	edits := []ft.Edit{}
+
+	for i, d := len(word), len(path); i > 0 || d > 0; {
+		cost := rows[d][i]
+
+		// Matching characters and substitutions first, so an edit isn't reported where none was needed
+		if i > 0 && d > 0 {
+			w, char := word[i-1], path[d-1]
+			if w == char && rows[d-1][i-1] == cost {
+				i--
+				d--
+				continue
+			}
+			if w != char && rows[d-1][i-1]+fmc.editCost(ft.EditSubstitution, string(w), string(char)) == cost {
+				edits = append(edits, ft.Edit{Operation: ft.EditSubstitution, Position: i - 1, From: string(w), To: string(char)})
+				i--
+				d--
+				continue
+			}
+		}
+
+		if i >= 2 && d >= 2 && word[i-1] != word[i-2] && word[i-1] == path[d-2] && word[i-2] == path[d-1] {
+			from, to := string(word[i-2:i]), string(path[d-2:d])
+			if rows[d-2][i-2]+fmc.editCost(ft.EditTransposition, from, to) == cost {
+				edits = append(edits, ft.Edit{Operation: ft.EditTransposition, Position: i - 2, From: from, To: to})
+				i -= 2
+				d -= 2
+				continue
+			}
+		}
+
+		if edit, ok := fmc.automatonOcrEdit(search, i, d, cost); ok {
+			edits = append(edits, edit)
+			i -= len([]rune(edit.From))
+			d -= len([]rune(edit.To))
+			continue
+		}
+
+		// Otherwise it was a skip or an insertion, only skips are left once the path has run out
+		if i > 0 && (d == 0 || rows[d][i-1]+fmc.editCost(ft.EditSkip, string(word[i-1]), "") == cost) {
+			edits = append(edits, ft.Edit{Operation: ft.EditSkip, Position: i - 1, From: string(word[i-1])})
+			i--
+			continue
+		}
+
+		edits = append(edits, ft.Edit{Operation: ft.EditInsertion, Position: i, To: string(path[d-1])})
+		d--
+	}
+
+	// Edits were found from the end of the word back
+	for left, right := 0, len(edits)-1; left < right; left, right = left+1, right-1 {
+		edits[left], edits[right] = edits[right], edits[left]
+	}
+
+	return edits
+}
+
+// Returns the OCR misread that ends at i in the word and d in the path and reaches cost, if there is one
+func (fmc *FuzzyMatcherCore[T]) automatonOcrEdit(search *automatonSearch, i, d int, cost float64) (ft.Edit, bool) {
+	if search.ocr == nil {
+		return ft.Edit{}, false
+	}
+
+	for _, confusion := range search.ocr[i] {
+		if !pathEndsWith(search.path[:d], confusion.to) {
+			continue
+		}
+
+		start := i - len(confusion.from)
+		operation := confusion.operation()
+		if search.rows[d-len(confusion.to)][start]+fmc.editCost(operation, string(confusion.from), string(confusion.to)) == cost {
+			return ft.Edit{Operation: operation, Position: start, From: string(confusion.from), To: string(confusion.to)}, true
+		}
+	}
+
+	return ft.Edit{}, false
+}
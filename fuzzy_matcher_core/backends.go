@@ -0,0 +1,267 @@
+package fuzzymatchercore
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	ft "github.com/oiamo123/fuzzy_matcher/fuzzy_types"
+)
+
+const (
+	DefaultSymSpellMaxEdits int     = 2
+	MaxSymSpellDepth        int     = 4 // Most deletes a SymSpell index stores for each value, the index grows exponentially with it
+	DefaultNGramSize        int     = 3
+	DefaultNGramMinOverlap  float64 = 0.5
+)
+
+// Field backends are kept alongside the trie rather than in place of it
+// The trie still holds every value, so removal by ID, expiry, snapshots and audits work the same for every field
+// Insert and detachID keep the backends up to date, the caller must hold the write lock for all of these
+
+// metricBackend is a backend indexed with a metric, it has to forget the metric when RegisterMetric replaces it
+type metricBackend interface {
+	dropMetric(method ft.CalculationMethod)
+}
+
+// Creates the backend for a field, returns an error if the kind doesn't exist
+func (fmc *FuzzyMatcherCore[T]) newFieldBackend(key ft.Field, kind ft.BackendKind) (ft.FieldBackend, error) {
+	switch kind {
+	case ft.SymSpellBackend:
+		maxEdits := fmc.CoreParams.SymSpellMaxEdits
+		if maxEdits <= 0 {
+			maxEdits = DefaultSymSpellMaxEdits
+		}
+
+		// Free edits would need every delete of every value
+		cheapest := editDistanceCheapest(fmc.CoreParams.EditCosts)
+		if cheapest <= 0 {
+			return nil, fmt.Errorf("backend for %s uses %q which needs every edit to cost more than 0", key, kind)
+		}
+		if depth := editsWithin(maxEdits, cheapest); depth > MaxSymSpellDepth {
+			return nil, fmt.Errorf("backend for %s uses %q which would need %d deletes of every value, more than %d", key, kind, depth, MaxSymSpellDepth)
+		}
+		return newSymSpellIndex(maxEdits, fmc.CoreParams.EditCosts), nil
+	case ft.BKTreeBackend:
+		return newBKTree(fmc.metric), nil
+	case ft.NGramBackend:
+		size := fmc.CoreParams.NGramSize
+		if size <= 0 {
+			size = DefaultNGramSize
+		}
+
+		minOverlap := fmc.CoreParams.NGramMinOverlap
+		if minOverlap == 0 {
+			minOverlap = DefaultNGramMinOverlap
+		}
+		if minOverlap < 0 || minOverlap > 1 {
+			return nil, fmt.Errorf("n-gram min overlap %v must be between 0 and 1", minOverlap)
+		}
+
+		return newNGramIndex(size, minOverlap), nil
+	}
+
+	return nil, fmt.Errorf("backend for %s uses %q which is not a field backend", key, kind)
+}
+
+// Creates the backends of fields that don't have one yet
+func (fmc *FuzzyMatcherCore[T]) prepareBackends() error {
+	for key, kind := range fmc.CoreParams.FieldBackends {
+		if kind == ft.TrieBackend || fmc.backends[key] != nil {
+			continue
+		}
+
+		backend, err := fmc.newFieldBackend(key, kind)
+		if err != nil {
+			return err
+		}
+
+		if fmc.backends == nil {
+			fmc.backends = make(map[ft.Field]ft.FieldBackend)
+		}
+		fmc.backends[key] = backend
+	}
+
+	return nil
+}
+
+// Builds every backend from the end of string nodes holding each ID, used when a snapshot is loaded
+func (fmc *FuzzyMatcherCore[T]) buildBackends(idNodes map[int][]*ft.FuzzyMatcherNode) (map[ft.Field]ft.FieldBackend, error) {
+	backends := make(map[ft.Field]ft.FieldBackend)
+	for key, kind := range fmc.CoreParams.FieldBackends {
+		if kind == ft.TrieBackend {
+			continue
+		}
+
+		backend, err := fmc.newFieldBackend(key, kind)
+		if err != nil {
+			return nil, err
+		}
+		backends[key] = backend
+	}
+
+	if len(backends) == 0 {
+		return nil, nil
+	}
+
+	for id, nodes := range idNodes {
+		for _, node := range nodes {
+			if backend, value, ok := splitBackendWord(backends, nodeWord(node)); ok {
+				backend.Insert(value, id)
+			}
+		}
+	}
+
+	return backends, nil
+}
+
+// Adds an ID to the backend of a search string's field, if it has one
+func (fmc *FuzzyMatcherCore[T]) addToBackend(word string, ID int) {
+	if backend, value, ok := splitBackendWord(fmc.backends, word); ok {
+		backend.Insert(value, ID)
+	}
+}
+
+// Removes an ID from the backend of an end of string node's field, if it has one
+func (fmc *FuzzyMatcherCore[T]) removeFromBackend(node *ft.FuzzyMatcherNode, ID int) {
+	if len(fmc.backends) == 0 {
+		return
+	}
+
+	if backend, value, ok := splitBackendWord(fmc.backends, nodeWord(node)); ok {
+		backend.Remove(value, ID)
+	}
+}
+
+// Splits a search string into its field's backend and value
+// Sound codes are stored under 'field~method:' so they never have a backend
+func splitBackendWord(backends map[ft.Field]ft.FieldBackend, word string) (ft.FieldBackend, string, bool) {
+	key, value, ok := strings.Cut(word, ":")
+	if !ok {
+		return nil, "", false
+	}
+
+	backend, ok := backends[ft.Field(key)]
+	return backend, value, ok
+}
+
+// Returns the most edits that fit in a budget when none costs less than cheapest
+func editsWithin(budget int, cheapest float64) int {
+	return int(math.Floor(float64(budget)/cheapest + 1e-9))
+}
+
+// Returns the search string an end of string node was inserted with
+func nodeWord(node *ft.FuzzyMatcherNode) string {
+	labels := [][]rune{}
+	for n := node; n.Parent != nil; n = n.Parent {
+		labels = append(labels, n.Label())
+	}
+
+	word := []rune{}
+	for i := len(labels) - 1; i >= 0; i-- {
+		word = append(word, labels[i]...)
+	}
+
+	return string(word)
+}
+
+// Returns the cheapest cost of turning word into value with the given edit costs, and the edits taken when explain is set
+// Counts the same edits as the trie's engines apart from OCR misreads, positions are in word
+func editDistance(costs ft.EditCosts, word, value []rune, explain bool) (float64, []ft.Edit) {
+	// distances[i][j] is the cheapest way to turn word[:i] into value[:j]
+	distances := make([][]float64, len(word)+1)
+	for i := range distances {
+		distances[i] = make([]float64, len(value)+1)
+		if i > 0 {
+			distances[i][0] = distances[i-1][0] + costs.Cost(ft.EditSkip, string(word[i-1]), "")
+		}
+	}
+	for j := 1; j <= len(value); j++ {
+		distances[0][j] = distances[0][j-1] + costs.Cost(ft.EditInsertion, "", string(value[j-1]))
+	}
+
+	for i := 1; i <= len(word); i++ {
+		for j := 1; j <= len(value); j++ {
+			substitution := distances[i-1][j-1]
+			if word[i-1] != value[j-1] {
+				substitution += costs.Cost(ft.EditSubstitution, string(word[i-1]), string(value[j-1]))
+			}
+
+			distances[i][j] = min(
+				substitution,
+				distances[i-1][j]+costs.Cost(ft.EditSkip, string(word[i-1]), ""),
+				distances[i][j-1]+costs.Cost(ft.EditInsertion, "", string(value[j-1])),
+			)
+
+			if swapped(word, value, i, j) {
+				distances[i][j] = min(distances[i][j], distances[i-2][j-2]+costs.Cost(ft.EditTransposition, string(word[i-2:i]), string(value[j-2:j])))
+			}
+		}
+	}
+
+	cost := distances[len(word)][len(value)]
+	if !explain {
+		return cost, nil
+	}
+
+	// Walk back from the end, matching characters and substitutions first so an edit isn't reported where none was needed
+	edits := []ft.Edit{}
+	for i, j := len(word), len(value); i > 0 || j > 0; {
+		current := distances[i][j]
+
+		switch {
+		case i > 0 && j > 0 && word[i-1] == value[j-1] && distances[i-1][j-1] == current:
+			i--
+			j--
+		case i > 0 && j > 0 && word[i-1] != value[j-1] && distances[i-1][j-1]+costs.Cost(ft.EditSubstitution, string(word[i-1]), string(value[j-1])) == current:
+			edits = append(edits, ft.Edit{Operation: ft.EditSubstitution, Position: i - 1, From: string(word[i-1]), To: string(value[j-1])})
+			i--
+			j--
+		case swapped(word, value, i, j) && distances[i-2][j-2]+costs.Cost(ft.EditTransposition, string(word[i-2:i]), string(value[j-2:j])) == current:
+			edits = append(edits, ft.Edit{Operation: ft.EditTransposition, Position: i - 2, From: string(word[i-2 : i]), To: string(value[j-2 : j])})
+			i -= 2
+			j -= 2
+		case i > 0 && (j == 0 || distances[i-1][j]+costs.Cost(ft.EditSkip, string(word[i-1]), "") == current):
+			edits = append(edits, ft.Edit{Operation: ft.EditSkip, Position: i - 1, From: string(word[i-1])})
+			i--
+		default:
+			edits = append(edits, ft.Edit{Operation: ft.EditInsertion, Position: i, To: string(value[j-1])})
+			j--
+		}
+	}
+
+	for left, right := 0, len(edits)-1; left < right; left, right = left+1, right-1 {
+		edits[left], edits[right] = edits[right], edits[left]
+	}
+
+	return cost, edits
+}
+
+// Returns the least any edit editDistance counts can cost, OCR misreads aren't counted so their costs are left out
+func editDistanceCheapest(costs ft.EditCosts) float64 {
+	cheapest := 1.0
+	for _, operation := range []ft.EditOperation{ft.EditSkip, ft.EditInsertion, ft.EditSubstitution, ft.EditTransposition} {
+		if cost, ok := costs.Operations[operation]; ok {
+			cheapest = min(cheapest, cost)
+		}
+	}
+
+	for pair, cost := range costs.Pairs {
+		from, to := []rune(pair.From), []rune(pair.To)
+
+		// Skips, insertions, substitutions and swaps of two characters
+		counted := len(from)+len(to) == 1 ||
+			len(from) == 1 && len(to) == 1 && from[0] != to[0] ||
+			len(from) == 2 && len(to) == 2 && swapped(from, to, 2, 2)
+		if counted {
+			cheapest = min(cheapest, cost)
+		}
+	}
+
+	return max(cheapest, 0)
+}
+
+// Returns true if the two characters of word before i are the two characters of value before j swapped
+func swapped(word, value []rune, i, j int) bool {
+	return i >= 2 && j >= 2 && word[i-1] != word[i-2] && word[i-1] == value[j-2] && word[i-2] == value[j-1]
+}
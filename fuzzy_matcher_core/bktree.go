@@ -0,0 +1,217 @@
+package fuzzymatchercore
+
+import (
+	"math"
+	"sort"
+	"sync"
+
+	ft "github.com/oiamo123/fuzzy_matcher/fuzzy_types"
+)
+
+// Slack on the search radius so rounding in a metric doesn't prune a value right on the min distance
+const bkTreeTolerance float64 = 1e-9
+
+// bkTree is a Burkhard-Keller tree over a field's values for each calculation method it has been searched with
+// Distances are 1 - similarity, so a search can prune whole branches with the triangle inequality
+// rather than comparing the query against every value
+type bkTree struct {
+	metric func(ft.CalculationMethod) ft.Metric
+	values map[string]map[int]bool // IDs holding each value, removed values stay in the trees until they're rebuilt
+	mu     sync.Mutex              // Guards trees, searches under the read lock may build one at the same time
+	trees  map[ft.CalculationMethod]*bkRoot
+}
+
+// bkRoot is the tree for one calculation method
+type bkRoot struct {
+	node *bkNode
+	size int // Values in the tree, removed ones included
+}
+
+// bkNode is a value in the tree, no two of its children are the same distance from it
+type bkNode struct {
+	value    string
+	children []bkChild
+}
+
+type bkChild struct {
+	distance float64
+	node     *bkNode
+}
+
+func newBKTree(metric func(ft.CalculationMethod) ft.Metric) *bkTree {
+	return &bkTree{
+		metric: metric,
+		values: make(map[string]map[int]bool),
+		trees:  make(map[ft.CalculationMethod]*bkRoot),
+	}
+}
+
+// Adds an ID to a value, adding the value to every tree built so far when it has no other IDs
+func (b *bkTree) Insert(value string, id int) {
+	ids, ok := b.values[value]
+	if !ok {
+		ids = make(map[int]bool)
+		b.values[value] = ids
+
+		for method, root := range b.trees {
+			b.add(root, value, b.metric(method))
+		}
+	}
+
+	ids[id] = true
+}
+
+// Removes an ID from a value, the value is left in the trees and skipped by searches once it has no IDs
+// Trees are dropped once removed values outnumber the rest, the next search rebuilds them
+func (b *bkTree) Remove(value string, id int) {
+	ids, ok := b.values[value]
+	if !ok {
+		return
+	}
+
+	delete(ids, id)
+	if len(ids) > 0 {
+		return
+	}
+
+	delete(b.values, value)
+	for method, root := range b.trees {
+		// Every value left is in each tree, the rest of the tree was removed
+		if root.size-len(b.values) > len(b.values) {
+			delete(b.trees, method)
+		}
+	}
+}
+
+// Drops the tree for a calculation method, used when its metric is replaced
+func (b *bkTree) dropMetric(method ft.CalculationMethod) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.trees, method)
+}
+
+/*
+BKTREE SEARCH FLOW
+1. Get the tree for the field's calculation method, building it from every value the first time the method is used
+2. Values with a similarity of at least MinDistance are within 1 - MinDistance of the query
+3. Walk the tree from the root, working out the query's distance to each value
+	- Values still holding IDs and within the radius are matches, only they count against the search budget
+	- A child d away from its parent can only hold values within the radius if d is within the radius of the query's distance to the parent
+	- Pruning relies on the triangle inequality, metrics that don't keep to it such as Jaro-Winkler can miss values near the min distance
+*/
+
+// Finds the values at least params.MinDistance similar to the query value, safe to call under the read lock
+// Values aren't matched by edits, so matches have no edit count and explanations have no edits
+func (b *bkTree) Search(params ft.RecurseParameters) []ft.MatchCandidate {
+	word := string(params.Word[len(params.Key)+1:])
+	metric := b.metric(params.CalculationMethod)
+
+	// 1.
+	root := b.tree(params.CalculationMethod, metric)
+	if root == nil {
+		return nil
+	}
+
+	// 2.
+	radius := 1 - params.MinDistance + bkTreeTolerance
+
+	// 3.
+	found := []string{}
+	stack := []*bkNode{root.node}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		live := len(b.values[node.value]) > 0
+		if live && !params.Budget.Visit() {
+			break
+		}
+
+		similarity := metric.Similarity(word, node.value)
+		if live && similarity >= params.MinDistance {
+			found = append(found, node.value)
+		}
+
+		distance := 1 - similarity
+		for _, child := range node.children {
+			if math.Abs(child.distance-distance) <= radius {
+				stack = append(stack, child.node)
+			}
+		}
+	}
+	sort.Strings(found)
+
+	matches := make([]ft.MatchCandidate, 0, len(found))
+	for _, value := range found {
+		ids := make([]int, 0, len(b.values[value]))
+		for id := range b.values[value] {
+			ids = append(ids, id)
+		}
+		sort.Ints(ids)
+
+		matches = append(matches, ft.MatchCandidate{
+			Text: string(params.Key) + ":" + value,
+			ID:   ids,
+		})
+	}
+
+	return matches
+}
+
+// Returns the tree for a calculation method, building it the first time it's asked for
+func (b *bkTree) tree(method ft.CalculationMethod, metric ft.Metric) *bkRoot {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if root, ok := b.trees[method]; ok {
+		return root
+	}
+
+	// Sorted so the tree has the same shape every time it's built
+	values := make([]string, 0, len(b.values))
+	for value := range b.values {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+
+	if len(values) == 0 {
+		return nil
+	}
+
+	root := &bkRoot{node: &bkNode{value: values[0]}, size: 1}
+	for _, value := range values[1:] {
+		b.add(root, value, metric)
+	}
+	b.trees[method] = root
+
+	return root
+}
+
+// Adds a value below root, following the child the same distance away at each node
+// Values removed and inserted again are already in the tree, so they aren't added twice
+func (b *bkTree) add(root *bkRoot, value string, metric ft.Metric) {
+	node := root.node
+	for {
+		if node.value == value {
+			return
+		}
+
+		distance := 1 - metric.Similarity(value, node.value)
+
+		next := (*bkNode)(nil)
+		for _, child := range node.children {
+			if child.distance == distance {
+				next = child.node
+				break
+			}
+		}
+
+		if next == nil {
+			node.children = append(node.children, bkChild{distance: distance, node: &bkNode{value: value}})
+			root.size++
+			return
+		}
+		node = next
+	}
+}
@@ -16,7 +16,7 @@ BREADTH-FIRST-SEARCH FLOW
    4.1. Get the highest priority node
    4.2. Process the node
    4.3. Expand the node's children
-   4.4. Early exit if we're at maxEdits-1 and the current node's children doesn't contain the current character
+   4.4. Early exit if there's room for one more edit and the current node's children doesn't contain the current character
    4.5. Compute the current nodes score using prefix prediction / similarity
    4.6. Add the new branch to the priority queue
 5. Add the node back to the visited array
@@ -37,6 +37,7 @@ func (fmc *FuzzyMatcherCore[T]) BreadthFirstSearch(params ft.RecurseParameters)
 	// 3.
 	key := fmc.MakeKey(params.Index, params.NumEdits, params.Depth, int(params.Node.Char))
 	delete(params.Visited, key)
+	cheapest := fmc.CoreParams.EditCosts.Cheapest()
 
 	// 4.
 	for maxHeap.Len() > 0 {
@@ -50,14 +51,22 @@ func (fmc *FuzzyMatcherCore[T]) BreadthFirstSearch(params ft.RecurseParameters)
 		matches = append(matches, match...)
 
 		if !ok {
+			// Nothing left in the queue will be expanded once the budget is spent
+			if nodePriority.Params.Budget.Stopped() {
+				break
+			}
 			continue
 		} 
 
 		// 4.3
-		for ch, child := range node.Children {
+		stop := false
+		node.EachChild(func(child *ft.FuzzyMatcherNode) bool {
+			ch := child.Char
+
 			// 4.4
-			if params.NumEdits == params.MaxEdits-1 && params.Node.Children[child.Char] == nil {
-				return matches
+			if params.LastEdit(cheapest) && params.Node.Child(ch) == nil {
+				stop = true
+				return false
 			}
 
 			branch := nodePriority.Params.Clone()
@@ -68,22 +77,24 @@ func (fmc *FuzzyMatcherCore[T]) BreadthFirstSearch(params ft.RecurseParameters)
 			branch.NumEditsIncrement = 0
 			
 			if branch.Index-1 < len(branch.Word) && ch != branch.Word[branch.Index-1] {
-    			branch.NumEditsIncrement = 1
-    			branch.DepthIncrement = 1
+				fmc.applyEdit(&branch, ft.EditSubstitution, branch.Index-1, string(branch.Word[branch.Index-1]), string(ch))
+			} else if branch.Index-1 >= len(branch.Word) {
+				fmc.recordEdit(&branch, ft.EditExtension, branch.Index-1, "", string(ch))
 			}
 
 			// 4.5
+			// The expanded node is the parent, compact nodes at the end of an edge point to the start of it
 			score := fmc.ComputeScore(
 				branch.Path,
 				branch.Word,
 				branch.Key,
-				branch.Node.Parent,
+				node,
 				branch.Node,
 				branch.CalculationMethod,
 			)
 
 			if len(strings.Split(string(branch.Path), ":")[1]) >= 4 && score < float64(params.MinDistance) {
-				continue
+				return true
 			}
 
 			// 4.6
@@ -91,6 +102,12 @@ func (fmc *FuzzyMatcherCore[T]) BreadthFirstSearch(params ft.RecurseParameters)
 				Params: branch,
 				Score:  score,
 			})
+
+			return true
+		})
+
+		if stop {
+			return matches
 		}
 	}
 
@@ -8,44 +8,92 @@ import (
 )
 
 // Propogate backwards to prune the fuzzy matcher
+// The caller must hold the write lock
+// Pruned nodes hold no IDs, so counts along the path are already up to date once detachID has run
+// Compact nodes that can't be pruned are merged into their only child instead
 func (fmc *FuzzyMatcherCore[T]) Prune(node *ft.FuzzyMatcherNode) {
 	if node == nil {
 		return
 	}
 
 	// If the node is an end of string or has 1 or more children or has an ID, it cannot be pruned
-	if node.IsEndofString || len(node.Children) >= 1 || len(node.ID) > 0 {
+	if node.IsEndofString || node.NumChildren() >= 1 || node.NumIDs() > 0 {
+		node.Compress()
 		return
 	}
 
 	// Else remove the node from its parent's children and continue pruning
 	if node.Parent != nil {
-		delete(node.Parent.Children, node.Char)
+		node.Parent.RemoveChild(node)
 		fmc.Prune(node.Parent)
 	}
 }
 
+// Removes an ID from the end of a word, decrementing the counts along its path and pruning what's left empty
+// Returns false if the word wasn't in the trie for the ID, the caller must hold the write lock
+func (fmc *FuzzyMatcherCore[T]) removePath(word string, ID int) bool {
+	node := fmc.findNode(word)
+	if node == nil || !node.HasID(ID) {
+		return false
+	}
+
+	fmc.detachID(node, ID)
+	return true
+}
+
+// Removes an ID from an end of string node that holds it, decrementing the counts along its path and pruning what's left empty
+// The caller must hold the write lock
+func (fmc *FuzzyMatcherCore[T]) detachID(node *ft.FuzzyMatcherNode, ID int) {
+	node.RemoveID(ID)
+	fmc.unindexNode(node, ID)
+	fmc.removeFromBackend(node, ID)
+
+	for n := node; n.Parent != nil; n = n.Parent {
+		n.Count--
+	}
+
+	if node.NumIDs() == 0 {
+		node.IsEndofString = false
+		fmc.Prune(node)
+	}
+}
+
 // Cleans up the fuzzy matcher by removing expired entries
+// The write lock is only taken when at least one entry has expired
 func (fmc *FuzzyMatcherCore[T]) Clean() {
 	if !fmc.CoreParams.UseExpiration {
 		return
 	}
 
-	if fmc.Root == nil {
+	now := time.Now()
+	mu := fmc.lock()
+
+	mu.RLock()
+	expired := fmc.Root != nil && fmc.ExpiryHeap.Len() > 0 && fmc.ExpiryHeap[0].Expiry.Before(now)
+	mu.RUnlock()
+
+	if !expired {
 		return
 	}
 
-	now := time.Now()
+	mu.Lock()
+	defer mu.Unlock()
+
 	for fmc.ExpiryHeap.Len() > 0 && fmc.ExpiryHeap[0].Expiry.Before(now) {
 		entry := heap.Pop(&fmc.ExpiryHeap).(ft.ExpiryEntry)
 
-		// Remove the ID from the node
-		delete(entry.Node.ID, entry.ID)
+		// Expiries for IDs that were removed or upserted since are stale
+		if !fmc.scheduled(entry) {
+			fmc.staleExpiries = max(fmc.staleExpiries-1, 0)
+			continue
+		}
 
-		if len(entry.Node.ID) == 0 {
-			// If the node has no IDs left, prune it
-			entry.Node.IsEndofString = false
-			fmc.Prune(entry.Node)
+		fmc.detachID(entry.Node, entry.ID)
+
+		// Once every field of an entry has expired the entry can't be matched anymore
+		if len(fmc.idNodes[entry.ID]) == 0 {
+			delete(fmc.Entries, entry.ID)
+			delete(fmc.expiries, entry.ID)
 		}
 	}
 }
@@ -53,7 +101,7 @@ func (fmc *FuzzyMatcherCore[T]) Clean() {
 // Cleans up the matched entries by removing those that exceed max edits or have empty fields
 func (fmc *FuzzyMatcherCore[T]) CleanMatches(
 	matchedEntries map[int]map[ft.Field]string,
-	matchedEntriesCount map[int]map[ft.Field]int,
+	matchedEntriesCount map[int]map[ft.Field]float64,
 	fuzzyEntry *ft.FuzzyEntry,
 ) map[int]map[ft.Field]string {
 	if len(matchedEntries) == 0 {
@@ -66,14 +114,14 @@ func (fmc *FuzzyMatcherCore[T]) CleanMatches(
 		shouldDelete := false
 
 		// Check total edits for the entire entry first
-		totalNumEdits := 0
+		totalNumEdits := 0.0
 		for _, count := range matchedEntriesCount[id] {
 			totalNumEdits += count
 		}
 
 		totalEdits := totalNumEdits
 
-		if totalEdits > fmc.CoreParams.MaxEdits {
+		if ft.ExceedsEdits(totalEdits, fmc.CoreParams.MaxEdits) {
 			shouldDelete = true
 		}
 
@@ -91,6 +139,10 @@ func (fmc *FuzzyMatcherCore[T]) CleanMatches(
 
 // Removes specified entries
 func (fmc *FuzzyMatcherCore[T]) RemoveEntries(entries []T) {
+	mu := fmc.lock()
+	mu.Lock()
+	defer mu.Unlock()
+
 	root := fmc.Root
 	if root == nil {
 		return
@@ -100,35 +152,68 @@ func (fmc *FuzzyMatcherCore[T]) RemoveEntries(entries []T) {
 	for _, entry := range entries {
 		fuzzyEntry := entry.CreateFuzzyEntry()
 
+		// drop the entry's expiries the same way RemoveByID does, so they can't expire the ID if it's inserted again
+		fmc.dropExpiries(fuzzyEntry.ID)
+
 		// loop over each key/field
 		for key, field := range fuzzyEntry.Key {
-			// create the search string
-			normalized := fmc.NormalizeField(field)
-			searchString := string(key) + ":" + normalized
-
-			// traverse the trie
-			node := root
-			for _, char := range searchString {
-				char = rune(char)
-				child, ok := node.Children[char]
-
-				if !ok {
-					break
-				}
-
-				node = child
+			// create the search strings
+			normalized := fmc.NormalizeFieldFor(key, field)
+
+			for _, searchString := range fmc.searchStrings(key, normalized) {
+				// traverse the trie
+				node := root
+				for _, char := range searchString {
+					char = rune(char)
+					child := node.Child(char)
+
+					if child == nil {
+						break
+					}
 
-				if node.IsEndofString {
-					delete(node.ID, fuzzyEntry.ID)     // delete the id from the endofstring node
-					delete(fmc.Entries, fuzzyEntry.ID) // delete the entry
+					node = child
 
-					if len(node.ID) == 0 {
-						// If the node has no IDs left, prune it
-						node.IsEndofString = false
-						fmc.Prune(node)
+					if node.IsEndofString {
+						// delete the id from the endofstring node, decrementing counts and pruning it if it's empty
+						if node.HasID(fuzzyEntry.ID) {
+							fmc.detachID(node, fuzzyEntry.ID)
+						}
+						delete(fmc.Entries, fuzzyEntry.ID) // delete the entry
 					}
 				}
 			}
 		}
 	}
-}
\ No newline at end of file
+
+	fmc.compactExpiries()
+}
+/*
+REMOVE BY ID FLOW
+1. Look up the end of string nodes holding the ID in the reverse index
+2. Remove the ID from each node, decrementing counts and pruning empty nodes
+3. Drop the entry
+	- Its expiries are left in the heap for Clean to skip, the heap is compacted once they make up half of it
+*/
+
+// Removes entries by ID, without needing the original entries
+func (fmc *FuzzyMatcherCore[T]) RemoveByID(ids ...int) {
+	mu := fmc.lock()
+	mu.Lock()
+	defer mu.Unlock()
+
+	for _, id := range ids {
+		// 1.
+		nodes := append([]*ft.FuzzyMatcherNode(nil), fmc.idNodes[id]...)
+		fmc.dropExpiries(id)
+
+		// 2.
+		for _, node := range nodes {
+			fmc.detachID(node, id)
+		}
+
+		// 3.
+		delete(fmc.Entries, id)
+	}
+
+	fmc.compactExpiries()
+}
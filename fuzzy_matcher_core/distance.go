@@ -1,6 +1,9 @@
 package fuzzymatchercore
 
 import (
+	"fmt"
+	"unicode/utf8"
+
 	ft "github.com/oiamo123/fuzzy_matcher/fuzzy_types"
 
 	"github.com/antzucaro/matchr"
@@ -14,25 +17,105 @@ func maxInt(a, b int) int {
 	return b
 }
 
-// Calculate the distance between 2 strings based on the specified method
-// Returns a similarity score between 0 and 1 where 1 is a 100% match
-func (fmc *FuzzyMatcherCore[T]) CalculateSimilarity(s1, s2 string, distanceMethod ft.CalculationMethod) float64 {
-	switch distanceMethod {
-	case ft.JaroWinkler:
-		return matchr.JaroWinkler(s1, s2, true)
-
-	case ft.Levenshtein:
-		maxLen := maxInt(len(s1), len(s2))
-		if maxLen == 0 {
-			return 1
-		}
+// Metrics available to every fuzzy matcher, metrics registered on a core take precedence
+var builtinMetrics = map[ft.CalculationMethod]ft.Metric{
+	ft.JaroWinkler:        ft.SimilarityFunc(jaroWinklerSimilarity),
+	ft.Levenshtein:        ft.SimilarityFunc(levenshteinSimilarity),
+	ft.DamerauLevenshtein: ft.SimilarityFunc(damerauLevenshteinSimilarity),
+	ft.Soundex:            phoneticMetric(ft.Soundex),
+	ft.Metaphone:          phoneticMetric(ft.Metaphone),
+	ft.NYSIIS:             phoneticMetric(ft.NYSIIS),
+}
 
-		dist := matchr.Levenshtein(s1, s2)
-		sim := 1.0 - float64(dist)/float64(maxLen)
+// Used for ft.Default and any method that isn't registered
+var defaultMetric = ft.SimilarityFunc(func(s1, s2 string) float64 {
+	return 1
+})
 
-		return sim
+func jaroWinklerSimilarity(s1, s2 string) float64 {
+	return matchr.JaroWinkler(s1, s2, true)
+}
 
-	default:
+func levenshteinSimilarity(s1, s2 string) float64 {
+	maxLen := maxInt(utf8.RuneCountInString(s1), utf8.RuneCountInString(s2))
+	if maxLen == 0 {
 		return 1
 	}
+
+	dist := matchr.Levenshtein(s1, s2)
+	sim := 1.0 - float64(dist)/float64(maxLen)
+
+	return sim
+}
+
+func damerauLevenshteinSimilarity(s1, s2 string) float64 {
+	maxLen := maxInt(utf8.RuneCountInString(s1), utf8.RuneCountInString(s2))
+	if maxLen == 0 {
+		return 1
+	}
+
+	dist := matchr.DamerauLevenshtein(s1, s2)
+	sim := 1.0 - float64(dist)/float64(maxLen)
+
+	return sim
+}
+
+// Registers a metric under the given name so it can be referenced from FuzzyMatcherParameters.CalculationMethods
+// Registering a built in name such as ft.JaroWinkler replaces it for this fuzzy matcher only
+func (fmc *FuzzyMatcherCore[T]) RegisterMetric(name ft.CalculationMethod, metric ft.Metric) error {
+	if name == ft.Default {
+		return fmt.Errorf("cannot register a metric under the default calculation method")
+	}
+
+	if metric == nil {
+		return fmt.Errorf("metric %q is nil", name)
+	}
+
+	mu := fmc.lock()
+	mu.Lock()
+	defer mu.Unlock()
+
+	if fmc.metrics == nil {
+		fmc.metrics = make(map[ft.CalculationMethod]ft.Metric)
+	}
+
+	fmc.metrics[name] = metric
+
+	// Backends indexed with the old metric would prune with the wrong distances
+	for _, backend := range fmc.backends {
+		if backend, ok := backend.(metricBackend); ok {
+			backend.dropMetric(name)
+		}
+	}
+
+	return nil
+}
+
+// Returns the metric for the calculation method, falling back to the built in metrics and then the default
+// The caller must hold the lock
+func (fmc *FuzzyMatcherCore[T]) metric(distanceMethod ft.CalculationMethod) ft.Metric {
+	if metric, ok := fmc.metrics[distanceMethod]; ok {
+		return metric
+	}
+
+	if metric, ok := builtinMetrics[distanceMethod]; ok {
+		return metric
+	}
+
+	return defaultMetric
+}
+
+// Calculate the distance between 2 strings based on the specified method
+// Returns a similarity score between 0 and 1 where 1 is a 100% match
+func (fmc *FuzzyMatcherCore[T]) CalculateSimilarity(s1, s2 string, distanceMethod ft.CalculationMethod) float64 {
+	mu := fmc.lock()
+	mu.RLock()
+	defer mu.RUnlock()
+
+	return fmc.calculateSimilarity(s1, s2, distanceMethod)
+}
+
+// The caller must hold the lock, searches already do
+func (fmc *FuzzyMatcherCore[T]) calculateSimilarity(s1, s2 string, distanceMethod ft.CalculationMethod) float64 {
+	return fmc.metric(distanceMethod).Similarity(s1, s2)
 }
@@ -2,20 +2,32 @@ package fuzzymatchercore
 
 import (
 	"container/heap"
+	"context"
+	"errors"
 	"fmt"
-	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	ft "github.com/oiamo123/fuzzy_matcher/fuzzy_types"
 )
 
 // FuzzyMatcherCore represents the core structure of the fuzzy matcher
+// It is safe for concurrent use once built through its methods: searches share a read lock,
+// while Build, RemoveEntries and Clean take the write lock
 type FuzzyMatcherCore[T ft.FuzzyMatcherDataSource] struct {
 	Root               *ft.FuzzyMatcherNode
 	CoreParams         ft.FuzzyMatcherCoreParameters[T]
 	ExpiryHeap         ExpiryHeap
 	Entries            map[int]T
+
+	mu      *sync.RWMutex
+	metrics map[ft.CalculationMethod]ft.Metric
+	idNodes map[int][]*ft.FuzzyMatcherNode // End of string nodes holding each ID
+	ocrConfusions map[rune][]ocrConfusion  // OCR misreads by their first character, compiled when the core is built or loaded
+	backends map[ft.Field]ft.FieldBackend  // Indexes searched instead of the trie for some fields, see FieldBackends
+	expiries map[int]time.Time             // Expiry each ID is scheduled for, expiries in the heap for any other time are stale
+	staleExpiries int                      // Stale expiries left in the heap by removals and upserts
 }
 
 const (
@@ -23,94 +35,232 @@ const (
 	MaxEdits          int                  = 2
 	MinDistance       float32              = 0.8
 	CalculationMethod ft.CalculationMethod = ft.JaroWinkler
+	DefaultResultLimit int                 = 5
+	DefaultSynonymScore float64            = 0.9
+)
+
+var (
+	ErrSearchTruncated    = errors.New("search truncated")
+	ErrNodeBudgetExceeded = errors.New("node budget exceeded")
 )
 
+// Guards lazy creation of each core's lock so zero-value cores stay usable
+var coreLockInit sync.Mutex
+
+// Returns the lock guarding the trie, expiry heap and entries, creating it on first use
+func (fmc *FuzzyMatcherCore[T]) lock() *sync.RWMutex {
+	coreLockInit.Lock()
+	defer coreLockInit.Unlock()
+
+	if fmc.mu == nil {
+		fmc.mu = &sync.RWMutex{}
+	}
+
+	return fmc.mu
+}
+
 // Inserts a word into the fuzzy matcher
+// The caller must hold the write lock, Build takes care of this
+// Counts along the path are only incremented the first time an ID is inserted for a word
 func (fmc *FuzzyMatcherCore[T]) Insert(word string, ID int) *ft.FuzzyMatcherNode {
 	node := fmc.Root
 
-	for _, char := range word {
-		c := rune(char)
+	if node.Layout() == ft.CompactLayout {
+		node = node.InsertCompact([]rune(word))
+	} else {
+		for _, char := range word {
+			c := rune(char)
 
-		if node.Children[c] == nil {
-			node.Children[c] = &ft.FuzzyMatcherNode{
-				Children: make(map[rune]*ft.FuzzyMatcherNode),
-				Char:     c,
-				Parent:   node,
-				Count:    0,
+			if node.Children[c] == nil {
+				node.Children[c] = ft.NewNode(ft.MapLayout, []rune{c}, node)
 			}
-		}
 
-		node = node.Children[c]
-		node.Count++
+			node = node.Children[c]
+		}
 	}
 
 	// Mark the last node with the entry ID
-	if node.ID == nil {
-		node.ID = make(map[int]bool)
+	if !node.AddID(ID) {
+		return node
 	}
 
-	node.ID[ID] = true
+	fmc.indexNode(node, ID)
+	fmc.addToBackend(word, ID)
+
+	// Count the new ID on every node along the path, the root isn't counted
+	for n := node; n.Parent != nil; n = n.Parent {
+		n.Count++
+	}
 
 	return node
 }
 
-// Builds the fuzzy matcher with a list of fuzzy entries
-func (fmc *FuzzyMatcherCore[T]) Build(entries []T) error {
+// Creates the root and expiry heap if needed and validates the core parameters
+// The caller must hold the write lock
+func (fmc *FuzzyMatcherCore[T]) prepare() error {
+	// Nothing is set up if the parameters are bad
+	if err := fmc.validate(); err != nil {
+		return err
+	}
+
 	// Init the expiry heap if it is nil
 	if fmc.ExpiryHeap == nil && fmc.CoreParams.UseExpiration {
 		heap.Init(&fmc.ExpiryHeap)
 	}
 
+	if fmc.expiries == nil {
+		fmc.expiries = make(map[int]time.Time)
+	}
+
 	// Init the root node if it is nil
 	if fmc.Root == nil {
-		fmc.Root = &ft.FuzzyMatcherNode{
-			Children: make(map[rune]*ft.FuzzyMatcherNode),
-		}
+		fmc.Root = ft.NewRootNode(fmc.CoreParams.NodeLayout)
+	}
+
+	if fmc.Entries == nil {
+		fmc.Entries = make(map[int]T)
+	}
+
+	fmc.ocrConfusions = fmc.compileOcrConfusions()
+
+	return fmc.prepareBackends()
+}
+
+// Returns an error if the core's parameters can't be used, backends are checked when they're created
+func (fmc *FuzzyMatcherCore[T]) validate() error {
+	if err := fmc.validateSearchEngine(); err != nil {
+		return err
+	}
+
+	return fmc.validatePhoneticIndexes()
+}
+
+// Builds the fuzzy matcher with a list of fuzzy entries
+func (fmc *FuzzyMatcherCore[T]) Build(entries []T) error {
+	mu := fmc.lock()
+	mu.Lock()
+	defer mu.Unlock()
+
+	if err := fmc.prepare(); err != nil {
+		return err
 	}
 
 	// Insert each word into the fuzzy matcher
 	for _, entry := range entries {
 		fuzzyEntry := entry.CreateFuzzyEntry()
+
+		// Building an ID again with a new expiry leaves its old expiries stale
+		if fmc.CoreParams.UseExpiration {
+			if scheduled, ok := fmc.expiries[fuzzyEntry.ID]; ok && !scheduled.Equal(fuzzyEntry.Expiry) {
+				fmc.staleExpiries += len(fmc.idNodes[fuzzyEntry.ID])
+			}
+			fmc.expiries[fuzzyEntry.ID] = fuzzyEntry.Expiry
+		}
+
 		for key, field := range fuzzyEntry.Key {
-			// Prefix the string with the field name ie 'firstname:'
-			normalized := fmc.NormalizeField(field)
-			searchString := string(key) + ":" + normalized
+			// Prefix the string with the field name ie 'firstname:', plus any sound codes ie 'firstname~soundex:'
+			normalized := fmc.NormalizeFieldFor(key, field)
 
-			node := fmc.Insert(searchString, fuzzyEntry.ID)
+			for _, searchString := range fmc.searchStrings(key, normalized) {
+				node := fmc.Insert(searchString, fuzzyEntry.ID)
 
-			node.IsEndofString = true
+				node.IsEndofString = true
 
-			// Create an expiry for the entry
-			if fmc.CoreParams.UseExpiration {
-				if fuzzyEntry.Expiry.IsZero() {
-					return fmt.Errorf("UseExpiration set to true. Cannot insert entry with no expiry: %v", entry)
-				}
+				// Create an expiry for the entry
+				if fmc.CoreParams.UseExpiration {
+					if fuzzyEntry.Expiry.IsZero() {
+						return fmt.Errorf("UseExpiration set to true. Cannot insert entry with no expiry: %v", entry)
+					}
 
-				heap.Push(&fmc.ExpiryHeap, ft.ExpiryEntry{
-					Node:   node,
-					Expiry: fuzzyEntry.Expiry,
-					ID:     fuzzyEntry.ID,
-				})
+					heap.Push(&fmc.ExpiryHeap, ft.ExpiryEntry{
+						Node:   node,
+						Expiry: fuzzyEntry.Expiry,
+						ID:     fuzzyEntry.ID,
+					})
+				}
 			}
 		}
 
-		if fmc.Entries == nil {
-			fmc.Entries = make(map[int]T)
-		}
-
 		fmc.Entries[fuzzyEntry.ID] = entry
 	}
 
 	return nil
 }
 
+// Creates the parameters to recursively search the trie for a normalized field value
+func (fmc *FuzzyMatcherCore[T]) NewRecurseParameters(key ft.Field, normalized string, parameters ft.FuzzyMatcherParameters) ft.RecurseParameters {
+	// Indexed by rune so values outside of ascii line up with the word
+	searchString := []rune(string(key) + ":" + normalized)
+
+	valueStart := len([]rune(key)) + 1
+	editableFields := make([]bool, len(searchString))
+	numEdits, numEditsOk := parameters.MaxEdits[key]
+
+	// Initialize editableFields based on the search parameters
+	for i := valueStart; i < len(editableFields); i++ {
+		if numEditsOk && numEdits > 0 {
+			editableFields[i] = true
+		} else {
+			editableFields[i] = false
+		}
+	}
+
+	recurseParameters := ft.RecurseParameters{
+		Word: searchString,
+		Key:  []rune(key),
+		Index: 0,
+		Node: fmc.Root,
+		Path: make([]rune, 0),
+		MaxDepth: parameters.MaxDepth[key],
+		Depth: 0,
+		DepthIncrement: 0,
+		NumEdits: 0,
+		MaxEdits: parameters.MaxEdits[key],
+		NumEditsIncrement: 0,
+		EditableFields: editableFields,
+		Visited: make(map[ft.VisitKey]struct{}),
+		Explored: make(map[ft.ExploredState]ft.ExploredCost),
+		CalculationMethod: parameters.CalculationMethods[key],
+		MinDistance:       parameters.MinDistances[key],
+	}
+
+	return recurseParameters
+}
+
 // Searches the fuzzy matcher for the given entry
-func (fmc *FuzzyMatcherCore[T]) SearchFuzzy(entry ft.FuzzyMatcherDataSource) (bool, []ft.FuzzyMatch[T]) {
+// Only the first set of options is used, if none are given the top 5 matches are returned
+func (fmc *FuzzyMatcherCore[T]) SearchFuzzy(entry ft.FuzzyMatcherDataSource, opts ...ft.SearchOptions) (bool, []ft.FuzzyMatch[T]) {
+	found, matches, _ := fmc.SearchContext(context.Background(), entry, opts...)
+	return found, matches
+}
+
+// Searches the fuzzy matcher for the given entry, stopping once ctx is done or SearchOptions.MaxNodes nodes have been visited
+// A stopped search returns the matches found so far with an error wrapping ErrSearchTruncated
+func (fmc *FuzzyMatcherCore[T]) SearchContext(ctx context.Context, entry ft.FuzzyMatcherDataSource, opts ...ft.SearchOptions) (bool, []ft.FuzzyMatch[T], error) {
+	options := resolveSearchOptions(opts)
+
+	if err := ctx.Err(); err != nil {
+		return false, nil, fmt.Errorf("%w: %w", ErrSearchTruncated, err)
+	}
+
 	if fmc.CoreParams.UseExpiration {
 		fmc.Clean()
 	}
 
+	mu := fmc.lock()
+	mu.RLock()
+	defer mu.RUnlock()
+
+	if fmc.Root == nil {
+		return false, nil, nil
+	}
+
+	// Searches that can't be stopped skip the budget entirely
+	var budget *ft.SearchBudget
+	if ctx.Done() != nil || options.MaxNodes > 0 {
+		budget = &ft.SearchBudget{Ctx: ctx, MaxNodes: int64(options.MaxNodes)}
+	}
+
 	fuzzyEntry := entry.CreateFuzzyEntry()
 	parameters := entry.GetSearchParameters()
 
@@ -123,41 +273,15 @@ func (fmc *FuzzyMatcherCore[T]) SearchFuzzy(entry ft.FuzzyMatcherDataSource) (bo
 		go func(key ft.Field, field string) {
 			defer wg.Done()
 
-			normalized := fmc.NormalizeField(field)
-			searchString := string(key) + ":" + normalized
-
-			valueStart := len(key) + 1
-			editableFields := make([]bool, len(searchString))
-			numEdits, numEditsOk := parameters.MaxEdits[key]
-
-			// Initialize editableFields based on the search parameters
-			for i := valueStart; i < len(editableFields); i++ {
-				if numEditsOk && numEdits > 0 {
-					editableFields[i] = true
-				} else {
-					editableFields[i] = false
-				}
-			}
-
-			recurseParameters := ft.RecurseParameters{
-				Word: []rune(searchString),
-				Key:  []rune(key),
-				Index: 0,
-				Node: fmc.Root,
-				Path: make([]rune, 0),
-				MaxDepth: parameters.MaxDepth[key],
-				Depth: 0,
-				DepthIncrement: 0,
-				NumEdits: 0,
-				MaxEdits: parameters.MaxEdits[key],
-				NumEditsIncrement: 0,
-				EditableFields: editableFields,
-				Visited: make(map[ft.VisitKey]struct{}),
-				CalculationMethod: parameters.CalculationMethods[key],
-				MinDistance:       parameters.MinDistances[key],
-			}
+			normalized := fmc.NormalizeFieldFor(key, field)
+			recurseParameters := fmc.NewRecurseParameters(key, normalized, parameters)
+			recurseParameters.Explain = options.Explain
+			recurseParameters.Budget = budget
+			recurseParameters.MaxMatches = options.MaxFieldMatches
 
-			matches := fmc.Recurse(recurseParameters)
+			matches := fmc.searchValue(recurseParameters)
+			matches = append(matches, fmc.SearchPhonetic(key, normalized)...)
+			matches = append(matches, fmc.SearchSynonyms(recurseParameters, parameters)...)
 
 			results <- ft.FieldResult{Key: key, Matches: matches}
 		}(key, field)
@@ -169,8 +293,12 @@ func (fmc *FuzzyMatcherCore[T]) SearchFuzzy(entry ft.FuzzyMatcherDataSource) (bo
 		close(results)
 	}()
 
+	// Merge maps are pooled and reused by later searches
+	scratch := getSearchScratch()
+	defer scratch.release()
+
 	// Collect all results first (thread-safe)
-	allResults := make(map[ft.Field][]ft.MatchCandidate)
+	allResults := scratch.allResults
 	for res := range results {
 		if res.Err != nil {
 			// Handle error if needed
@@ -180,27 +308,44 @@ func (fmc *FuzzyMatcherCore[T]) SearchFuzzy(entry ft.FuzzyMatcherDataSource) (bo
 	}
 
 	// Now merge results sequentially (no race conditions)
-	matchedEntries := make(map[int]map[ft.Field]string)
-	matchedEntriesCount := make(map[int]map[ft.Field]int)
+	matchedEntries := scratch.matchedEntries
+	matchedEntriesCount := scratch.matchedEntriesCount
+	matchedSynonyms := scratch.matchedSynonyms
+	matchedCandidates := make(map[int]map[ft.Field]ft.MatchCandidate)
 
 	for key, matches := range allResults {
 		for _, match := range matches {
 			for _, id := range match.ID {
-				if match.EditCount > parameters.MaxEdits[key] {
+				if ft.ExceedsEdits(match.EditCount, parameters.MaxEdits[key]) {
 					continue
 				}
 
 				if matchedEntries[id] == nil {
-					matchedEntries[id] = make(map[ft.Field]string)
+					matchedEntries[id] = scratch.fieldStrings()
 				}
 				matchedEntries[id][key] = strings.Replace(match.Text, string(key)+":", "", 1)
 
 				if matchedEntriesCount[id] == nil {
-					matchedEntriesCount[id] = make(map[ft.Field]int)
+					matchedEntriesCount[id] = scratch.fieldCounts()
 				}
 
 				if currentCount, exists := matchedEntriesCount[id][key]; !exists || currentCount > match.EditCount {
 					matchedEntriesCount[id][key] = match.EditCount
+
+					// Explanations report the cheapest path to the entry
+					if options.Explain {
+						if matchedCandidates[id] == nil {
+							matchedCandidates[id] = make(map[ft.Field]ft.MatchCandidate)
+						}
+						matchedCandidates[id][key] = match
+					}
+				}
+
+				if match.Synonym != "" {
+					if matchedSynonyms[id] == nil {
+						matchedSynonyms[id] = scratch.fieldStrings()
+					}
+					matchedSynonyms[id][key] = match.Synonym
 				}
 			}
 		}
@@ -210,15 +355,26 @@ func (fmc *FuzzyMatcherCore[T]) SearchFuzzy(entry ft.FuzzyMatcherDataSource) (bo
 	// An entry is incomplete if it has any empty fields
 	matchedEntriesCleaned := fmc.CleanMatches(matchedEntries, matchedEntriesCount, fuzzyEntry)
 
+	// Partial results are still scored and returned alongside the error
+	var err error
+	if budget.Stopped() {
+		err = searchTruncatedError(ctx, budget)
+	}
+
 	if len(matchedEntriesCleaned) == 0 {
-		return false, nil
+		return false, nil, err
 	}
 
 	// track valid entries
-	finalMatchedEntries := []ft.FuzzyMatch[T]{}
+	finalMatchedEntries := []rankedMatch[T]{}
 
 	for id, match := range matchedEntriesCleaned {
 		similarities := make(map[ft.Field]float64)
+		synonyms := make(map[ft.Field]string)
+		var explanation map[ft.Field]ft.FieldExplanation
+		if options.Explain {
+			explanation = make(map[ft.Field]ft.FieldExplanation, len(fuzzyEntry.Key))
+		}
 		reject := false
 
 		// iterate through the keys
@@ -233,14 +389,44 @@ func (fmc *FuzzyMatcherCore[T]) SearchFuzzy(entry ft.FuzzyMatcherDataSource) (bo
 				break
 			}
 
-			matchNormalized := fmc.NormalizeField(matchVal)
-			originalNormalized := fmc.NormalizeField(origVal)
+			matchNormalized := fmc.NormalizeFieldFor(key, matchVal)
+			originalNormalized := fmc.NormalizeFieldFor(key, origVal)
+
+			similarity := fmc.calculateSimilarity(originalNormalized, matchNormalized, parameters.CalculationMethods[key])
+
+			// Matches found through a synonym are scored against the synonym, capped at the synonym score
+			if synonym, ok := matchedSynonyms[id][key]; ok {
+				synonymSimilarity := fmc.synonymScore() * fmc.calculateSimilarity(synonym, matchNormalized, parameters.CalculationMethods[key])
+				if synonymSimilarity > similarity {
+					similarity = synonymSimilarity
+					synonyms[key] = synonym
+				}
+			}
 
-			similarity := fmc.CalculateSimilarity(originalNormalized, matchNormalized, parameters.CalculationMethods[key])
 			if similarity < min {
 				similarity = 0
 			}
 
+			if explanation != nil {
+				candidate := matchedCandidates[id][key]
+				searched := originalNormalized
+				if candidate.Synonym != "" {
+					searched = candidate.Synonym
+				}
+
+				explanation[key] = ft.FieldExplanation{
+					Query:      originalNormalized,
+					Searched:   searched,
+					Matched:    matchNormalized,
+					Synonym:    synonyms[key],
+					Phonetic:   candidate.Phonetic,
+					Edits:      candidate.Edits,
+					Method:     parameters.CalculationMethods[key],
+					Similarity: similarity,
+					Weight:     parameters.Weights[key],
+				}
+			}
+
 			// if the min distance is not 0 and the distance == 0
 			if min == 0 && similarity == 0 {
 				continue
@@ -263,29 +449,61 @@ func (fmc *FuzzyMatcherCore[T]) SearchFuzzy(entry ft.FuzzyMatcherDataSource) (bo
 		for key, weight := range parameters.Weights {
 			if distance, exists := similarities[key]; exists {
 				score += weight * distance
+
+				if fieldExplanation, ok := explanation[key]; ok {
+					fieldExplanation.Contribution = weight * distance
+					explanation[key] = fieldExplanation
+				}
+			}
+		}
+
+		// Only report synonyms for fields that contributed to the score
+		for key := range synonyms {
+			if _, exists := similarities[key]; !exists {
+				delete(synonyms, key)
 			}
 		}
+		if len(synonyms) == 0 {
+			synonyms = nil
+		}
+
+		// skip entries below the overall score floor
+		if score < options.MinScore {
+			continue
+		}
+
+		totalEdits := 0.0
+		for _, count := range matchedEntriesCount[id] {
+			totalEdits += count
+		}
 
 		// add to list
-		finalMatchedEntries = append(finalMatchedEntries, ft.FuzzyMatch[T]{
-			Score: score,
-			Entry: fmc.Entries[id],
+		finalMatchedEntries = append(finalMatchedEntries, rankedMatch[T]{
+			ID:    id,
+			Edits: totalEdits,
+			Match: ft.FuzzyMatch[T]{
+				Score:    score,
+				Entry:    fmc.Entries[id],
+				Synonyms:    synonyms,
+				Explanation: explanation,
+			},
 		})
 	}
 
-	if len(finalMatchedEntries) == 0 {
-		return false, nil
+	// Return the requested page of the best matches
+	page := fmc.rankMatches(finalMatchedEntries, options)
+	if len(page) == 0 {
+		return false, nil, err
 	}
 
-	// Return top n best matches
-	sort.Slice(finalMatchedEntries, func(i, j int) bool {
-		return finalMatchedEntries[i].Score > finalMatchedEntries[j].Score
-	})
+	return true, page, err
+}
 
-	if len(finalMatchedEntries) > 5 {
-		finalMatchedEntries = finalMatchedEntries[:5]
+// Returns why a search stopped early
+func searchTruncatedError(ctx context.Context, budget *ft.SearchBudget) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%w after %d nodes: %w", ErrSearchTruncated, budget.Nodes(), err)
 	}
 
-	// return true, matchedEntries
-	return true, finalMatchedEntries
+	return fmt.Errorf("%w after %d nodes: %w", ErrSearchTruncated, budget.Nodes(), ErrNodeBudgetExceeded)
 }
@@ -0,0 +1,49 @@
+package fuzzymatchercore
+
+import (
+	ft "github.com/oiamo123/fuzzy_matcher/fuzzy_types"
+)
+
+// The reverse index maps each ID to the end of string nodes holding it, so entries can be removed by ID alone
+// Every place that adds an ID to a node or removes one from it must keep the index up to date
+// The caller must hold the write lock for all of these
+
+// Records that a node holds an ID
+func (fmc *FuzzyMatcherCore[T]) indexNode(node *ft.FuzzyMatcherNode, ID int) {
+	if fmc.idNodes == nil {
+		fmc.idNodes = make(map[int][]*ft.FuzzyMatcherNode)
+	}
+
+	fmc.idNodes[ID] = append(fmc.idNodes[ID], node)
+}
+
+// Records that a node no longer holds an ID
+func (fmc *FuzzyMatcherCore[T]) unindexNode(node *ft.FuzzyMatcherNode, ID int) {
+	nodes := fmc.idNodes[ID]
+	for i, indexed := range nodes {
+		if indexed == node {
+			nodes[i] = nodes[len(nodes)-1]
+			nodes[len(nodes)-1] = nil
+			nodes = nodes[:len(nodes)-1]
+			break
+		}
+	}
+
+	if len(nodes) == 0 {
+		delete(fmc.idNodes, ID)
+	} else {
+		fmc.idNodes[ID] = nodes
+	}
+}
+
+// Builds the reverse index for a list of nodes
+func buildIDIndex(nodes []*ft.FuzzyMatcherNode) map[int][]*ft.FuzzyMatcherNode {
+	idNodes := make(map[int][]*ft.FuzzyMatcherNode)
+	for _, node := range nodes {
+		for _, id := range node.IDs() {
+			idNodes[id] = append(idNodes[id], node)
+		}
+	}
+
+	return idNodes
+}
@@ -0,0 +1,171 @@
+package fuzzymatchercore
+
+import (
+	"math"
+	"sort"
+
+	ft "github.com/oiamo123/fuzzy_matcher/fuzzy_types"
+)
+
+// Pads either end of a value so its first and last characters are in as many n-grams as the rest
+const nGramPadding rune = 0
+
+// nGramIndex is an inverted index from each n-gram to the values holding it
+// A typo only breaks the n-grams around it, so a long value keeps most of its n-grams wherever the typo is,
+// including the first character, which the trie has to get right or pay for with an edit
+type nGramIndex struct {
+	size       int
+	minOverlap float64
+	values     map[string]map[int]bool // IDs holding each value
+	postings   map[string][]string     // Values holding each n-gram
+}
+
+func newNGramIndex(size int, minOverlap float64) *nGramIndex {
+	return &nGramIndex{
+		size:       size,
+		minOverlap: minOverlap,
+		values:     make(map[string]map[int]bool),
+		postings:   make(map[string][]string),
+	}
+}
+
+// Adds an ID to a value, indexing the value's n-grams the first time it's seen
+func (n *nGramIndex) Insert(value string, id int) {
+	ids, ok := n.values[value]
+	if !ok {
+		ids = make(map[int]bool)
+		n.values[value] = ids
+
+		for _, gram := range nGrams(value, n.size) {
+			n.postings[gram] = append(n.postings[gram], value)
+		}
+	}
+
+	ids[id] = true
+}
+
+// Removes an ID from a value, dropping the value's n-grams once it has no IDs left
+func (n *nGramIndex) Remove(value string, id int) {
+	ids, ok := n.values[value]
+	if !ok {
+		return
+	}
+
+	delete(ids, id)
+	if len(ids) > 0 {
+		return
+	}
+
+	delete(n.values, value)
+	for _, gram := range nGrams(value, n.size) {
+		values := n.postings[gram]
+		for i, stored := range values {
+			if stored == value {
+				values[i] = values[len(values)-1]
+				values = values[:len(values)-1]
+				break
+			}
+		}
+
+		if len(values) == 0 {
+			delete(n.postings, gram)
+		} else {
+			n.postings[gram] = values
+		}
+	}
+}
+
+/*
+NGRAM SEARCH FLOW
+1. Split the query value into its distinct n-grams
+2. Count the n-grams each value shares with the query from the postings of the query's n-grams
+3. Values sharing at least minOverlap of the query's n-grams are candidates
+4. Order the candidates by shared n-grams and keep MaxMatches of them
+	- Candidates aren't matched by edits, SearchFuzzy scores them with the field's calculation method and min distance
+*/
+
+// Finds the values sharing enough n-grams with the query value, safe to call under the read lock
+// Values aren't matched by edits, so matches have no edit count and explanations have no edits
+func (n *nGramIndex) Search(params ft.RecurseParameters) []ft.MatchCandidate {
+	// 1.
+	grams := nGrams(string(params.Word[len(params.Key)+1:]), n.size)
+	if len(grams) == 0 {
+		return nil
+	}
+
+	// 2.
+	shared := map[string]int{}
+	for _, gram := range grams {
+		if !params.Budget.Visit() {
+			break
+		}
+
+		for _, value := range n.postings[gram] {
+			shared[value]++
+		}
+	}
+
+	// 3.
+	needed := max(1, int(math.Ceil(n.minOverlap*float64(len(grams)))))
+	candidates := []string{}
+	for value, count := range shared {
+		if count >= needed {
+			candidates = append(candidates, value)
+		}
+	}
+
+	// 4.
+	sort.Slice(candidates, func(i, j int) bool {
+		if shared[candidates[i]] != shared[candidates[j]] {
+			return shared[candidates[i]] > shared[candidates[j]]
+		}
+		return candidates[i] < candidates[j]
+	})
+	if params.MaxMatches > 0 && len(candidates) > params.MaxMatches {
+		candidates = candidates[:params.MaxMatches]
+	}
+
+	matches := make([]ft.MatchCandidate, 0, len(candidates))
+	for _, value := range candidates {
+		ids := make([]int, 0, len(n.values[value]))
+		for id := range n.values[value] {
+			ids = append(ids, id)
+		}
+		sort.Ints(ids)
+
+		matches = append(matches, ft.MatchCandidate{
+			Text: string(params.Key) + ":" + value,
+			ID:   ids,
+		})
+	}
+
+	return matches
+}
+
+// Returns the distinct n-grams of a value padded at either end, nothing for an empty value
+func nGrams(value string, size int) []string {
+	if value == "" {
+		return nil
+	}
+
+	padded := []rune{}
+	for i := 0; i < size-1; i++ {
+		padded = append(padded, nGramPadding)
+	}
+	padded = append(padded, []rune(value)...)
+	for i := 0; i < size-1; i++ {
+		padded = append(padded, nGramPadding)
+	}
+
+	seen := map[string]bool{}
+	grams := []string{}
+	for i := 0; i+size <= len(padded); i++ {
+		gram := string(padded[i : i+size])
+		if !seen[gram] {
+			seen[gram] = true
+			grams = append(grams, gram)
+		}
+	}
+
+	return grams
+}
@@ -3,13 +3,131 @@ package fuzzymatchercore
 import (
 	"regexp"
 	"strings"
+	"unicode"
+
+	ft "github.com/oiamo123/fuzzy_matcher/fuzzy_types"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
 )
 
+var normalizeRegex = regexp.MustCompile(`[^a-zA-Z0-9]+`)
+
+// Transliterations for latin letters that don't decompose into a base letter and a diacritic
+var LatinTransliterations = map[rune]string{
+	'ß': "ss", 'ẞ': "SS",
+	'æ': "ae", 'Æ': "AE",
+	'œ': "oe", 'Œ': "OE",
+	'ø': "o", 'Ø': "O",
+	'đ': "d", 'Đ': "D",
+	'ð': "d", 'Ð': "D",
+	'ł': "l", 'Ł': "L",
+	'þ': "th", 'Þ': "TH",
+	'ı': "i",
+}
+
+var unicodeForms = map[ft.UnicodeForm]norm.Form{
+	ft.NFC:  norm.NFC,
+	ft.NFD:  norm.NFD,
+	ft.NFKC: norm.NFKC,
+	ft.NFKD: norm.NFKD,
+}
+
 // Normalizes an entry by converting it to lowercase and removing non-alphanumeric characters
 func (fmc *FuzzyMatcherCore[T]) NormalizeField(entry string) string {
-	normalizeRegex := regexp.MustCompile(`[^a-zA-Z0-9]+`)
 	lower := strings.ToLower(entry)
 	normalized := normalizeRegex.ReplaceAllString(lower, "")
 
 	return normalized
 }
+
+// Normalizes a field value with the field's pipeline, falling back to NormalizeField when it has none
+func (fmc *FuzzyMatcherCore[T]) NormalizeFieldFor(key ft.Field, entry string) string {
+	pipeline, ok := fmc.CoreParams.Normalization[key]
+	if !ok {
+		return fmc.NormalizeField(entry)
+	}
+
+	return Normalize(entry, pipeline)
+}
+
+/*
+NORMALIZE FLOW
+1. Transliterate runes
+2. Apply the unicode normalization form
+3. Fold diacritics by decomposing and dropping combining marks
+4. Fold case
+5. Apply the punctuation and whitespace policies
+*/
+
+// Normalizes a value with the given pipeline
+func Normalize(value string, pipeline ft.NormalizationPipeline) string {
+	// 1.
+	if len(pipeline.Transliterations) > 0 {
+		var sb strings.Builder
+		for _, r := range value {
+			if replacement, ok := pipeline.Transliterations[r]; ok {
+				sb.WriteString(replacement)
+			} else {
+				sb.WriteRune(r)
+			}
+		}
+		value = sb.String()
+	}
+
+	// 2.
+	form, hasForm := unicodeForms[pipeline.Unicode]
+	if hasForm {
+		value = form.String(value)
+	}
+
+	// 3.
+	if pipeline.FoldDiacritics {
+		decomposed := norm.NFD.String(value)
+		value = strings.Map(func(r rune) rune {
+			if unicode.Is(unicode.Mn, r) {
+				return -1
+			}
+			return r
+		}, decomposed)
+
+		// Put the value back into the requested form, decomposing may have undone it
+		if hasForm {
+			value = form.String(value)
+		} else {
+			value = norm.NFC.String(value)
+		}
+	}
+
+	// 4.
+	if pipeline.FoldCase {
+		value = cases.Fold().String(value)
+	}
+
+	// 5.
+	var sb strings.Builder
+	pendingSpace := false
+	for _, r := range value {
+		policy := ft.KeepCharacters
+		switch {
+		case unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.IsMark(r):
+		case unicode.IsSpace(r):
+			policy = pipeline.Whitespace
+		default:
+			policy = pipeline.Punctuation
+		}
+
+		switch policy {
+		case ft.KeepCharacters:
+			if pendingSpace && sb.Len() > 0 {
+				sb.WriteRune(' ')
+			}
+			pendingSpace = false
+			sb.WriteRune(r)
+		case ft.SpaceCharacters:
+			pendingSpace = true
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,162 @@
+package fuzzymatchercore
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	ft "github.com/oiamo123/fuzzy_matcher/fuzzy_types"
+)
+
+// Misreads corrected when CorrectOcrMisreads is set, unless ReplaceOcrConfusions is too
+var DefaultOcrConfusions = ft.OcrConfusions{
+	"0":  {"o", "d", "q"},
+	"1":  {"l", "i"},
+	"2":  {"z", "s"},
+	"3":  {"e", "8", "b"},
+	"4":  {"a", "h"},
+	"5":  {"s"},
+	"6":  {"b", "g", "G"},
+	"7":  {"t", "y"},
+	"8":  {"b", "3", "B"},
+	"9":  {"g", "q"},
+	"o":  {"0", "a"},
+	"i":  {"1", "l"},
+	"l":  {"1", "i"},
+	"b":  {"8", "3", "6"},
+	"g":  {"6", "9"},
+	"z":  {"2"},
+	"c":  {"e", "o"},
+	"s":  {"5"},
+	"n":  {"m", "r"},
+	"a":  {"o"},
+	"e":  {"c"},
+	"r":  {"n"},
+	"v":  {"u"},
+	"u":  {"v"},
+	"cl": {"d"},
+	"rn": {"m"},
+	"nn": {"m"},
+}
+
+// More misreads that aren't corrected by default, add them with FuzzyMatcherCoreParameters.OcrConfusions
+var ExtraOcrConfusions = ft.OcrConfusions{
+	"m":  {"rn", "nn"},
+	"vv": {"w"},
+	"w":  {"vv"},
+	"d":  {"cl"},
+}
+
+// ocrConfusion is a misread, from is what's in the search word and to is what's looked for in the trie
+type ocrConfusion struct {
+	from        []rune
+	to          []rune
+	probability float64 // 0 when the misread wasn't trained
+}
+
+// Returns the edit a confusion is recorded as
+func (c ocrConfusion) operation() ft.EditOperation {
+	if len(c.from) == 1 && len(c.to) == 1 {
+		return ft.EditOcrSingle
+	}
+
+	return ft.EditOcrMulti
+}
+
+// Returns true if the confusion starts at index in word
+func (c ocrConfusion) matches(word []rune, index int) bool {
+	if index+len(c.from) > len(word) {
+		return false
+	}
+
+	for i, r := range c.from {
+		if word[index+i] != r {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Loads OCR confusions from JSON, e.g. {"li": ["h"], "iii": ["m"]}
+// Values are trimmed but otherwise used as written, so they should be normalized the way the fields are
+func LoadOcrConfusions(r io.Reader) (ft.OcrConfusions, error) {
+	raw := map[string][]string{}
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("decoding ocr confusions: %w", err)
+	}
+
+	confusions := make(ft.OcrConfusions, len(raw))
+	for from, tos := range raw {
+		from = strings.TrimSpace(from)
+		if from == "" {
+			continue
+		}
+
+		for _, to := range tos {
+			to = strings.TrimSpace(to)
+			if to != from {
+				confusions[from] = append(confusions[from], to)
+			}
+		}
+	}
+
+	return confusions, nil
+}
+
+// Loads OCR confusions from a JSON file
+func LoadOcrConfusionsFile(path string) (ft.OcrConfusions, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return LoadOcrConfusions(file)
+}
+
+// Groups the core's confusions by their first character so only the ones starting at the current character are tried
+// Trained confusions below the min probability are left out, the rest are tried most likely first
+// Shorter confusions come first otherwise, then they're in order so searches are repeatable
+// The caller must hold the write lock
+func (fmc *FuzzyMatcherCore[T]) compileOcrConfusions() map[rune][]ocrConfusion {
+	confusions := fmc.CoreParams.OcrConfusions.Merge(fmc.CoreParams.OcrProbabilities.Confusions())
+	if !fmc.CoreParams.ReplaceOcrConfusions {
+		confusions = DefaultOcrConfusions.Merge(confusions)
+	}
+
+	froms := make([]string, 0, len(confusions))
+	for from := range confusions {
+		if from != "" {
+			froms = append(froms, from)
+		}
+	}
+	sort.Slice(froms, func(i, j int) bool {
+		if len(froms[i]) != len(froms[j]) {
+			return len(froms[i]) < len(froms[j])
+		}
+		return froms[i] < froms[j]
+	})
+
+	compiled := make(map[rune][]ocrConfusion, len(froms))
+	for _, from := range froms {
+		fromRunes := []rune(from)
+		for _, to := range confusions[from] {
+			probability, trained := fmc.CoreParams.OcrProbabilities[ft.EditPair{From: from, To: to}]
+			if trained && probability < fmc.CoreParams.MinOcrProbability {
+				continue
+			}
+
+			compiled[fromRunes[0]] = append(compiled[fromRunes[0]], ocrConfusion{from: fromRunes, to: []rune(to), probability: probability})
+		}
+	}
+
+	for _, bucket := range compiled {
+		sort.SliceStable(bucket, func(i, j int) bool { return bucket[i].probability > bucket[j].probability })
+	}
+
+	return compiled
+}
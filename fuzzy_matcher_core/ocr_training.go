@@ -0,0 +1,175 @@
+package fuzzymatchercore
+
+import (
+	"strings"
+
+	ft "github.com/oiamo123/fuzzy_matcher/fuzzy_types"
+)
+
+const (
+	DefaultOcrTrainingMaxLength int = 3
+	DefaultOcrTrainingMinCount  int = 1
+)
+
+// alignedStep is one step of an alignment, a read character, a corrected character or both
+type alignedStep struct {
+	read         rune
+	corrected    rune
+	hasRead      bool
+	hasCorrected bool
+}
+
+/*
+OCR TRAINING FLOW
+1. Align each read value with its corrected value using the fewest edits
+2. Group the edits between matching characters into misreads, e.g. "rn" read for "m"
+	- Nothing read means the OCR engine dropped characters, the character before is added to both sides
+	  so the misread can be found while searching, e.g. "m" read for "mi"
+3. Drop misreads that are too long or weren't seen often enough
+4. Divide the times each misread was seen by the times its characters were read at all
+*/
+
+// Learns how likely OCR misreads are from values as they were read and as they were corrected
+// Values are used as written, so they should be normalized the way the fields are, e.g. with NormalizeFieldFor
+func TrainOcrConfusions(pairs []ft.OcrTrainingPair, options ft.OcrTrainingOptions) ft.OcrProbabilities {
+	if options.MaxLength <= 0 {
+		options.MaxLength = DefaultOcrTrainingMaxLength
+	}
+	if options.MinCount <= 0 {
+		options.MinCount = DefaultOcrTrainingMinCount
+	}
+
+	counts := make(map[ft.EditPair]int)
+	for _, pair := range pairs {
+		// 1.
+		steps := alignOcrPair([]rune(pair.Read), []rune(pair.Corrected))
+
+		// 2.
+		for _, misread := range groupMisreads(steps) {
+			// 3.
+			if len([]rune(misread.From)) > options.MaxLength || len([]rune(misread.To)) > options.MaxLength {
+				continue
+			}
+			counts[misread]++
+		}
+	}
+
+	// 4.
+	reads := make(map[string]int)
+	probabilities := make(ft.OcrProbabilities)
+	for misread, count := range counts {
+		if count < options.MinCount {
+			continue
+		}
+
+		if _, ok := reads[misread.From]; !ok {
+			for _, pair := range pairs {
+				reads[misread.From] += strings.Count(pair.Read, misread.From)
+			}
+		}
+
+		probability := float64(count) / float64(reads[misread.From])
+		if probability > 1 {
+			probability = 1 // Overlapping reads like "iii" in "iiii" are only counted once
+		}
+		probabilities[misread] = probability
+	}
+
+	return probabilities
+}
+
+// Aligns read with corrected using the fewest insertions, deletions and substitutions
+func alignOcrPair(read, corrected []rune) []alignedStep {
+	// distances[i][j] is the edit distance between read[:i] and corrected[:j]
+	distances := make([][]int, len(read)+1)
+	for i := range distances {
+		distances[i] = make([]int, len(corrected)+1)
+		distances[i][0] = i
+	}
+	for j := range distances[0] {
+		distances[0][j] = j
+	}
+
+	for i := 1; i <= len(read); i++ {
+		for j := 1; j <= len(corrected); j++ {
+			substitution := distances[i-1][j-1]
+			if read[i-1] != corrected[j-1] {
+				substitution++
+			}
+			distances[i][j] = min(substitution, distances[i-1][j]+1, distances[i][j-1]+1)
+		}
+	}
+
+	// Walk back from the end, preferring matches and substitutions so misreads stay together
+	steps := []alignedStep{}
+	for i, j := len(read), len(corrected); i > 0 || j > 0; {
+		switch {
+		case i > 0 && j > 0 && distances[i][j] == distances[i-1][j-1] && read[i-1] == corrected[j-1],
+			i > 0 && j > 0 && distances[i][j] == distances[i-1][j-1]+1:
+			steps = append(steps, alignedStep{read: read[i-1], corrected: corrected[j-1], hasRead: true, hasCorrected: true})
+			i--
+			j--
+		case i > 0 && distances[i][j] == distances[i-1][j]+1:
+			steps = append(steps, alignedStep{read: read[i-1], hasRead: true})
+			i--
+		default:
+			steps = append(steps, alignedStep{corrected: corrected[j-1], hasCorrected: true})
+			j--
+		}
+	}
+
+	for left, right := 0, len(steps)-1; left < right; left, right = left+1, right-1 {
+		steps[left], steps[right] = steps[right], steps[left]
+	}
+
+	return steps
+}
+
+// Groups the edits between matching characters of an alignment into misreads
+func groupMisreads(steps []alignedStep) []ft.EditPair {
+	misreads := []ft.EditPair{}
+	var from, to []rune
+	var previous rune
+	hasPrevious := false
+
+	flush := func(next rune, hasNext bool) {
+		if len(from) == 0 && len(to) == 0 {
+			return
+		}
+
+		// Dropped characters are anchored to a character that was read
+		switch {
+		case len(from) > 0:
+		case hasPrevious:
+			from = []rune{previous}
+			to = append([]rune{previous}, to...)
+		case hasNext:
+			from = []rune{next}
+			to = append(to, next)
+		default:
+			from, to = nil, nil
+			return
+		}
+
+		misreads = append(misreads, ft.EditPair{From: string(from), To: string(to)})
+		from, to = nil, nil
+	}
+
+	for _, step := range steps {
+		if step.hasRead && step.hasCorrected && step.read == step.corrected {
+			flush(step.read, true)
+			previous, hasPrevious = step.read, true
+			continue
+		}
+
+		if step.hasRead {
+			from = append(from, step.read)
+		}
+		if step.hasCorrected {
+			to = append(to, step.corrected)
+		}
+	}
+	flush(0, false)
+
+	return misreads
+}
@@ -0,0 +1,498 @@
+package fuzzymatchercore
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"sort"
+	"time"
+
+	ft "github.com/oiamo123/fuzzy_matcher/fuzzy_types"
+)
+
+/*
+SNAPSHOT FORMAT
+1. Header
+	- 4 byte magic "FZMC" followed by a big endian uint16 format version
+2. Trie
+	- Nodes written depth first, each as: label, flags (end of string), count, IDs, number of children, children
+	- Version 2 writes the label as its length followed by its runes, version 1 wrote a single char
+3. Expiry heap
+	- Number of entries, then expiry (unix seconds and nanoseconds), node index (depth first order) and ID for each entry
+4. Entries
+	- Length prefixed gob encoding of the entry IDs in ascending order and their entries
+5. Trailer
+	- Big endian CRC-32 (IEEE) of sections 2 to 4
+
+Core parameters are not part of the snapshot, the caller configures them before loading
+*/
+
+const (
+	snapshotMagic          = "FZMC"
+	SnapshotVersion uint16 = 2
+)
+
+var (
+	ErrSnapshotFormat   = errors.New("snapshot is not a fuzzy matcher snapshot")
+	ErrSnapshotVersion  = errors.New("snapshot version is not supported")
+	ErrSnapshotChecksum = errors.New("snapshot checksum does not match")
+)
+
+const nodeIsEndOfString byte = 1 << 0
+
+// snapshotEntries holds the Entries map as parallel slices so snapshots are deterministic
+type snapshotEntries[T ft.FuzzyMatcherDataSource] struct {
+	IDs     []int
+	Entries []T
+}
+
+// checksumWriter writes varints through a buffered writer while hashing everything written
+type checksumWriter struct {
+	w   *bufio.Writer
+	crc hash.Hash32
+	buf [binary.MaxVarintLen64]byte
+}
+
+func (cw *checksumWriter) Write(p []byte) (int, error) {
+	cw.crc.Write(p)
+	return cw.w.Write(p)
+}
+
+func (cw *checksumWriter) writeUvarint(v uint64) error {
+	n := binary.PutUvarint(cw.buf[:], v)
+	_, err := cw.Write(cw.buf[:n])
+	return err
+}
+
+func (cw *checksumWriter) writeVarint(v int64) error {
+	n := binary.PutVarint(cw.buf[:], v)
+	_, err := cw.Write(cw.buf[:n])
+	return err
+}
+
+// checksumReader reads varints from a buffered reader while hashing everything read
+type checksumReader struct {
+	r   *bufio.Reader
+	crc hash.Hash32
+}
+
+func (cr *checksumReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.crc.Write(p[:n])
+	return n, err
+}
+
+func (cr *checksumReader) ReadByte() (byte, error) {
+	b, err := cr.r.ReadByte()
+	if err == nil {
+		cr.crc.Write([]byte{b})
+	}
+	return b, err
+}
+
+func (cr *checksumReader) readUvarint() (uint64, error) {
+	return binary.ReadUvarint(cr)
+}
+
+func (cr *checksumReader) readVarint() (int64, error) {
+	return binary.ReadVarint(cr)
+}
+
+// Saves the trie, expiry heap and entries to w
+func (fmc *FuzzyMatcherCore[T]) Save(w io.Writer) error {
+	mu := fmc.lock()
+	mu.RLock()
+	defer mu.RUnlock()
+
+	bw := bufio.NewWriter(w)
+
+	// 1.
+	header := make([]byte, 0, len(snapshotMagic)+2)
+	header = append(header, snapshotMagic...)
+	header = binary.BigEndian.AppendUint16(header, SnapshotVersion)
+	if _, err := bw.Write(header); err != nil {
+		return err
+	}
+
+	cw := &checksumWriter{w: bw, crc: crc32.NewIEEE()}
+
+	// 2.
+	root := fmc.Root
+	if root == nil {
+		root = ft.NewRootNode(fmc.CoreParams.NodeLayout)
+	}
+
+	nodeIndex := make(map[*ft.FuzzyMatcherNode]int)
+	if err := fmc.writeNode(cw, root, nodeIndex); err != nil {
+		return err
+	}
+
+	// 3.
+	// Entries pointing at nodes that have already been pruned, or left behind by removals and upserts, are stale and are dropped
+	expiries := make([]ft.ExpiryEntry, 0, len(fmc.ExpiryHeap))
+	for _, expiry := range fmc.ExpiryHeap {
+		if _, ok := nodeIndex[expiry.Node]; ok && fmc.scheduled(expiry) {
+			expiries = append(expiries, expiry)
+		}
+	}
+
+	if err := cw.writeUvarint(uint64(len(expiries))); err != nil {
+		return err
+	}
+
+	for _, expiry := range expiries {
+		if err := cw.writeVarint(expiry.Expiry.Unix()); err != nil {
+			return err
+		}
+		if err := cw.writeUvarint(uint64(expiry.Expiry.Nanosecond())); err != nil {
+			return err
+		}
+		if err := cw.writeUvarint(uint64(nodeIndex[expiry.Node])); err != nil {
+			return err
+		}
+		if err := cw.writeVarint(int64(expiry.ID)); err != nil {
+			return err
+		}
+	}
+
+	// 4.
+	sorted := snapshotEntries[T]{
+		IDs:     make([]int, 0, len(fmc.Entries)),
+		Entries: make([]T, 0, len(fmc.Entries)),
+	}
+	for id := range fmc.Entries {
+		sorted.IDs = append(sorted.IDs, id)
+	}
+	sort.Ints(sorted.IDs)
+	for _, id := range sorted.IDs {
+		sorted.Entries = append(sorted.Entries, fmc.Entries[id])
+	}
+
+	var entries bytes.Buffer
+	if err := gob.NewEncoder(&entries).Encode(sorted); err != nil {
+		return fmt.Errorf("encoding entries: %w", err)
+	}
+
+	if err := cw.writeUvarint(uint64(entries.Len())); err != nil {
+		return err
+	}
+	if _, err := cw.Write(entries.Bytes()); err != nil {
+		return err
+	}
+
+	// 5.
+	if err := binary.Write(bw, binary.BigEndian, cw.crc.Sum32()); err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+// Writes a node and its children depth first, recording the order each node was written in
+func (fmc *FuzzyMatcherCore[T]) writeNode(cw *checksumWriter, node *ft.FuzzyMatcherNode, nodeIndex map[*ft.FuzzyMatcherNode]int) error {
+	nodeIndex[node] = len(nodeIndex)
+
+	var flags byte
+	if node.IsEndofString {
+		flags |= nodeIsEndOfString
+	}
+
+	label := node.Label()
+	if err := cw.writeUvarint(uint64(len(label))); err != nil {
+		return err
+	}
+	for _, char := range label {
+		if err := cw.writeVarint(int64(char)); err != nil {
+			return err
+		}
+	}
+	if _, err := cw.Write([]byte{flags}); err != nil {
+		return err
+	}
+	if err := cw.writeUvarint(uint64(node.Count)); err != nil {
+		return err
+	}
+
+	ids := node.IDs()
+	sort.Ints(ids)
+
+	if err := cw.writeUvarint(uint64(len(ids))); err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if err := cw.writeVarint(int64(id)); err != nil {
+			return err
+		}
+	}
+
+	// Children are written in a fixed order so identical tries produce identical snapshots
+	children := make([]*ft.FuzzyMatcherNode, 0, node.NumChildren())
+	node.EachEdge(func(child *ft.FuzzyMatcherNode) {
+		children = append(children, child)
+	})
+	sort.Slice(children, func(i, j int) bool { return children[i].Label()[0] < children[j].Label()[0] })
+
+	if err := cw.writeUvarint(uint64(len(children))); err != nil {
+		return err
+	}
+	for _, child := range children {
+		if err := fmc.writeNode(cw, child, nodeIndex); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Loads a snapshot written by Save, replacing the trie, expiry heap and entries
+// The current state is left untouched if the snapshot can't be read
+func (fmc *FuzzyMatcherCore[T]) Load(r io.Reader) error {
+	// Bad parameters are reported before anything is read, the same as Build
+	if err := fmc.validate(); err != nil {
+		return err
+	}
+
+	br := bufio.NewReader(r)
+
+	// 1.
+	header := make([]byte, len(snapshotMagic)+2)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return fmt.Errorf("%w: reading header: %v", ErrSnapshotFormat, err)
+	}
+
+	if string(header[:len(snapshotMagic)]) != snapshotMagic {
+		return ErrSnapshotFormat
+	}
+
+	version := binary.BigEndian.Uint16(header[len(snapshotMagic):])
+	if version == 0 || version > SnapshotVersion {
+		return fmt.Errorf("%w: got version %d, expected %d or earlier", ErrSnapshotVersion, version, SnapshotVersion)
+	}
+
+	cr := &checksumReader{r: br, crc: crc32.NewIEEE()}
+
+	// 2.
+	nodes := []*ft.FuzzyMatcherNode{}
+	root, err := fmc.readNode(cr, version, nil, &nodes)
+	if err != nil {
+		return fmt.Errorf("%w: reading trie: %v", ErrSnapshotFormat, err)
+	}
+
+	// Snapshots written from the map layout have one node per rune, merge them back into edges
+	for _, node := range nodes {
+		node.Compress()
+	}
+
+	// 3.
+	numExpiries, err := cr.readUvarint()
+	if err != nil {
+		return fmt.Errorf("%w: reading expiry heap: %v", ErrSnapshotFormat, err)
+	}
+
+	expiryHeap := ExpiryHeap{}
+	for i := uint64(0); i < numExpiries; i++ {
+		seconds, err := cr.readVarint()
+		if err != nil {
+			return fmt.Errorf("%w: reading expiry heap: %v", ErrSnapshotFormat, err)
+		}
+
+		nanoseconds, err := cr.readUvarint()
+		if err != nil {
+			return fmt.Errorf("%w: reading expiry heap: %v", ErrSnapshotFormat, err)
+		}
+
+		index, err := cr.readUvarint()
+		if err != nil {
+			return fmt.Errorf("%w: reading expiry heap: %v", ErrSnapshotFormat, err)
+		}
+		if index >= uint64(len(nodes)) {
+			return fmt.Errorf("%w: expiry references node %d of %d", ErrSnapshotFormat, index, len(nodes))
+		}
+
+		id, err := cr.readVarint()
+		if err != nil {
+			return fmt.Errorf("%w: reading expiry heap: %v", ErrSnapshotFormat, err)
+		}
+
+		expiryHeap = append(expiryHeap, ft.ExpiryEntry{
+			Expiry: time.Unix(seconds, int64(nanoseconds)),
+			Node:   nodes[index],
+			ID:     int(id),
+		})
+	}
+
+	// 4.
+	entriesLen, err := cr.readUvarint()
+	if err != nil {
+		return fmt.Errorf("%w: reading entries: %v", ErrSnapshotFormat, err)
+	}
+
+	var entriesBuf bytes.Buffer
+	if _, err := io.CopyN(&entriesBuf, cr, int64(entriesLen)); err != nil {
+		return fmt.Errorf("%w: reading entries: %v", ErrSnapshotFormat, err)
+	}
+
+	// 5.
+	var checksum uint32
+	if err := binary.Read(br, binary.BigEndian, &checksum); err != nil {
+		return fmt.Errorf("%w: reading checksum: %v", ErrSnapshotFormat, err)
+	}
+
+	if checksum != cr.crc.Sum32() {
+		return ErrSnapshotChecksum
+	}
+
+	sorted := snapshotEntries[T]{}
+	if err := gob.NewDecoder(&entriesBuf).Decode(&sorted); err != nil {
+		return fmt.Errorf("%w: decoding entries: %v", ErrSnapshotFormat, err)
+	}
+	if len(sorted.IDs) != len(sorted.Entries) {
+		return fmt.Errorf("%w: %d entry IDs for %d entries", ErrSnapshotFormat, len(sorted.IDs), len(sorted.Entries))
+	}
+
+	entries := make(map[int]T, len(sorted.IDs))
+	for i, id := range sorted.IDs {
+		entries[id] = sorted.Entries[i]
+	}
+
+	heap.Init(&expiryHeap)
+	idNodes := buildIDIndex(nodes)
+
+	// Snapshots only hold scheduled expiries, so each ID's are all for the same time
+	scheduled := make(map[int]time.Time)
+	for _, expiry := range expiryHeap {
+		scheduled[expiry.ID] = expiry.Expiry
+	}
+
+	// Backends aren't saved, they're rebuilt from the values in the trie
+	backends, err := fmc.buildBackends(idNodes)
+	if err != nil {
+		return err
+	}
+
+	mu := fmc.lock()
+	mu.Lock()
+	defer mu.Unlock()
+
+	fmc.Root = root
+	fmc.ExpiryHeap = expiryHeap
+	fmc.expiries = scheduled
+	fmc.staleExpiries = 0
+	fmc.Entries = entries
+	fmc.idNodes = idNodes
+	fmc.backends = backends
+
+	return fmc.prepare()
+}
+
+// Reads a node and its children depth first, appending each node to nodes in the order it was read
+// Nodes are created in the configured layout, in the map layout a label is read into a chain of nodes and the last one is appended
+func (fmc *FuzzyMatcherCore[T]) readNode(cr *checksumReader, version uint16, parent *ft.FuzzyMatcherNode, nodes *[]*ft.FuzzyMatcherNode) (*ft.FuzzyMatcherNode, error) {
+	label, err := readLabel(cr, version)
+	if err != nil {
+		return nil, err
+	}
+
+	flags, err := cr.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	count, err := cr.readUvarint()
+	if err != nil {
+		return nil, err
+	}
+
+	layout := fmc.CoreParams.NodeLayout
+
+	var top, node *ft.FuzzyMatcherNode
+	switch {
+	case parent == nil:
+		node = ft.NewRootNode(layout)
+		top = node
+	case len(label) == 0:
+		return nil, errors.New("node has an empty label")
+	case layout == ft.CompactLayout:
+		node = ft.NewNode(layout, label, parent)
+		node.Count = int(count)
+		top = node
+	default:
+		// Runes inside an edge lead only to the node at its end, so they share its count
+		node = parent
+		for _, char := range label {
+			node = ft.NewNode(layout, []rune{char}, node)
+			node.Count = int(count)
+			if top == nil {
+				top = node
+			} else {
+				node.Parent.AddChild(node)
+			}
+		}
+	}
+
+	node.IsEndofString = flags&nodeIsEndOfString != 0
+	*nodes = append(*nodes, node)
+
+	numIDs, err := cr.readUvarint()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := uint64(0); i < numIDs; i++ {
+		id, err := cr.readVarint()
+		if err != nil {
+			return nil, err
+		}
+		node.AddID(int(id))
+	}
+
+	numChildren, err := cr.readUvarint()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := uint64(0); i < numChildren; i++ {
+		child, err := fmc.readNode(cr, version, node, nodes)
+		if err != nil {
+			return nil, err
+		}
+		node.AddChild(child)
+	}
+
+	return top, nil
+}
+
+// Reads a node's label, version 1 snapshots hold a single char per node
+func readLabel(cr *checksumReader, version uint16) ([]rune, error) {
+	if version == 1 {
+		char, err := cr.readVarint()
+		if err != nil {
+			return nil, err
+		}
+		return []rune{rune(char)}, nil
+	}
+
+	length, err := cr.readUvarint()
+	if err != nil {
+		return nil, err
+	}
+
+	// The label isn't preallocated, a corrupt length shouldn't cause a huge allocation
+	label := []rune{}
+	for i := uint64(0); i < length; i++ {
+		char, err := cr.readVarint()
+		if err != nil {
+			return nil, err
+		}
+		label = append(label, rune(char))
+	}
+
+	return label, nil
+}
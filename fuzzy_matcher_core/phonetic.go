@@ -0,0 +1,170 @@
+package fuzzymatchercore
+
+import (
+	"fmt"
+	"strings"
+
+	ft "github.com/oiamo123/fuzzy_matcher/fuzzy_types"
+
+	"github.com/antzucaro/matchr"
+)
+
+// Encoders for each phonetic calculation method, double metaphone can produce two codes
+var phoneticEncoders = map[ft.CalculationMethod]func(string) []string{
+	ft.Soundex: func(s string) []string {
+		return []string{matchr.Soundex(s)}
+	},
+	ft.Metaphone: func(s string) []string {
+		primary, secondary := matchr.DoubleMetaphone(s)
+		return []string{primary, secondary}
+	},
+	ft.NYSIIS: func(s string) []string {
+		return []string{matchr.NYSIIS(s)}
+	},
+}
+
+// Returns the distinct, non empty, lowercase sound codes for the value
+func phoneticCodes(value string, method ft.CalculationMethod) []string {
+	encode, ok := phoneticEncoders[method]
+	if !ok || value == "" {
+		return nil
+	}
+
+	codes := []string{}
+	for _, code := range encode(value) {
+		code = strings.ToLower(code)
+		if code == "" {
+			continue
+		}
+
+		duplicate := false
+		for _, existing := range codes {
+			if existing == code {
+				duplicate = true
+				break
+			}
+		}
+
+		if !duplicate {
+			codes = append(codes, code)
+		}
+	}
+
+	return codes
+}
+
+// Builds a metric that compares sound codes, identical codes are a 100% match
+// Otherwise the closest pair of codes is scored by levenshtein similarity
+func phoneticMetric(method ft.CalculationMethod) ft.Metric {
+	return ft.SimilarityFunc(func(s1, s2 string) float64 {
+		if s1 == s2 {
+			return 1
+		}
+
+		best := 0.0
+		for _, c1 := range phoneticCodes(s1, method) {
+			for _, c2 := range phoneticCodes(s2, method) {
+				if sim := levenshteinSimilarity(c1, c2); sim > best {
+					best = sim
+				}
+			}
+		}
+
+		return best
+	})
+}
+
+// Returns an error if a phonetic index is configured with a method that can't encode
+func (fmc *FuzzyMatcherCore[T]) validatePhoneticIndexes() error {
+	for key, method := range fmc.CoreParams.PhoneticIndexes {
+		if _, ok := phoneticEncoders[method]; !ok {
+			return fmt.Errorf("phonetic index for %s uses %q which is not a phonetic calculation method", key, method)
+		}
+	}
+
+	return nil
+}
+
+// Prefixes a sound code with the field and method ie 'surname~soundex:'
+func (fmc *FuzzyMatcherCore[T]) phoneticSearchString(key ft.Field, method ft.CalculationMethod, code string) string {
+	return string(key) + "~" + string(method) + ":" + code
+}
+
+// Returns every string a normalized field value is stored under in the trie
+// The spelling is always first, followed by any sound codes when the field has a phonetic index
+func (fmc *FuzzyMatcherCore[T]) searchStrings(key ft.Field, normalized string) []string {
+	searchStrings := []string{string(key) + ":" + normalized}
+
+	method, ok := fmc.CoreParams.PhoneticIndexes[key]
+	if !ok {
+		return searchStrings
+	}
+
+	for _, code := range phoneticCodes(normalized, method) {
+		searchStrings = append(searchStrings, fmc.phoneticSearchString(key, method, code))
+	}
+
+	return searchStrings
+}
+
+// Walks the trie along the exact search string, returning nil if it isn't present
+func (fmc *FuzzyMatcherCore[T]) findNode(searchString string) *ft.FuzzyMatcherNode {
+	node := fmc.Root
+	for _, char := range searchString {
+		if node == nil {
+			return nil
+		}
+		node = node.Child(char)
+	}
+
+	return node
+}
+
+/*
+PHONETIC SEARCH FLOW
+1. Encode the query value with the field's phonetic method
+2. Look up each sound code in the trie
+3. Group the matching IDs by their stored spelling, phonetic matches don't count as edits
+*/
+
+// Finds the entries whose field sounds like the query value, the caller must hold the lock
+func (fmc *FuzzyMatcherCore[T]) SearchPhonetic(key ft.Field, normalized string) []ft.MatchCandidate {
+	method, ok := fmc.CoreParams.PhoneticIndexes[key]
+	if !ok {
+		return nil
+	}
+
+	// 1.
+	idsBySpelling := make(map[string][]int)
+	seen := make(map[int]bool)
+	for _, code := range phoneticCodes(normalized, method) {
+		// 2.
+		node := fmc.findNode(fmc.phoneticSearchString(key, method, code))
+		if node == nil || !node.IsEndofString {
+			continue
+		}
+
+		// 3.
+		for _, id := range node.IDs() {
+			entry, ok := fmc.Entries[id]
+			if !ok || seen[id] {
+				continue
+			}
+			seen[id] = true
+
+			spelling := fmc.NormalizeFieldFor(key, entry.CreateFuzzyEntry().Key[key])
+			idsBySpelling[spelling] = append(idsBySpelling[spelling], id)
+		}
+	}
+
+	matches := make([]ft.MatchCandidate, 0, len(idsBySpelling))
+	for spelling, ids := range idsBySpelling {
+		matches = append(matches, ft.MatchCandidate{
+			Text:     string(key) + ":" + spelling,
+			ID:       ids,
+			Phonetic: method,
+		})
+	}
+
+	return matches
+}
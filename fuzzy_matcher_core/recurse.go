@@ -4,49 +4,13 @@ import (
 	ft "github.com/oiamo123/fuzzy_matcher/fuzzy_types"
 )
 
-var ocrMisreads = map[rune][]rune{
-	'0': {'o', 'd', 'q'},
-	'1': {'l', 'i'},
-	'2': {'z', 's'},
-	'3': {'e', '8', 'b'},
-	'4': {'a', 'h'},
-	'5': {'s'},
-	'6': {'b', 'g', 'G'},
-	'7': {'t', 'y'},
-	'8': {'b', '3', 'B'},
-	'9': {'g', 'q'},
-	'o': {'0', 'a'},
-	'i': {'1', 'l'},
-	'l': {'1', 'i'},
-	'b': {'8', '3', '6'},
-	'g': {'6', '9'},
-	'z': {'2'},
-	'c': {'e', 'o'},
-	's': {'5'},
-	'n': {'m', 'r'},
-	'a': {'o'},
-	'e': {'c'},
-	'r': {'n'},
-	'v': {'u'},
-	'u': {'v'},
-}
-
-var multiCharMisreads = map[string][][]rune{
-	"m":  {{'r', 'n'}, {'n', 'n'}},
-	"cl": {{'d'}},
-	"rn": {{'m'}},
-	"nn": {{'m'}},
-	"w":  {{'v', 'v'}},
-	"d":  {{'c', 'l'}},
-}
-
 /*
 RECURSE FLOW:
 1. Perform BFS if the index has reached the end of the search word
 	- IE: Searching for "Mike", "Michael" is a match
 
-2. Early exit
-	- IE: We're at MaxEdits - 1 and the current nodes children doesn't contain the current character
+2. Look up the current character's child
+	- A missing child doesn't end the search, even with one edit left it can still be spent on a skip, swap, insertion, substitution or misread
 
 3. Perform Normal match
 	- IE: Current char is 'm' and current node has child node 'm'
@@ -56,15 +20,26 @@ RECURSE FLOW:
 	- IE: Searching for "Mike" and on 'i', we can skip to 'k'
 	4.2. Perform BFS
 	- IE: Searching for "Mike" and on 'i', we can perform BFS to find "Michael"
-
-5. If the fuzzy matcher is correcting OCR misreads, we can also:
-	5.1. Check for single-character ocr misreads
+	4.3. Swap the current and next characters
+	- IE: Searching for "Jhon" and on 'h', we can swap 'h' and 'o' to find "John"
+	4.4. Insert a character before the current one, staying on the current character
+	- IE: Searching for "Jon" and on 'n', we can insert 'h' to find "John"
+	4.5. Substitute the current character
+	- IE: Searching for "Jahn" and on 'a', we can substitute 'o' to find "John"
+	- Both are only tried while there's an edit left, and only on children the rest of the word carries on from
+	- IE: Inserting 'h' is only tried if the 'h' node has an 'n' child, substituting 'o' only if the 'o' node has an 'n' child
+
+5. If the fuzzy matcher is correcting OCR misreads, we can also replace any misread starting at the current character
 	- "Searching for "M1ke", switch the '1' out with an 'i'"
-	5.2. Check for multi-character ocr misreads
 	- "Searching for "Srnith", switch the 'rn' out with an 'm'"
 */
 
 func (fmc *FuzzyMatcherCore[T]) Recurse(params ft.RecurseParameters) []ft.MatchCandidate {
+	// Branches can reach the same state in different ways, e.g. an insertion then a skip or a substitution
+	if !params.Explore() {
+		return nil
+	}
+
 	// 1.
 	if params.Index >= len(params.Word) {
 		return fmc.BreadthFirstSearch(params.Clone())
@@ -75,18 +50,15 @@ func (fmc *FuzzyMatcherCore[T]) Recurse(params ft.RecurseParameters) []ft.MatchC
     	return matches // stop recursion
 	}
 
-	char := params.Word[params.Index]
-
 	// 2.
-	if params.NumEdits == params.MaxEdits-1 && params.Node.Children[char] == nil {
-		return matches
-	}
+	char := params.Word[params.Index]
+	next := params.Node.Child(char)
 
 	// 3.
-	if params.Node.Children[char] != nil {
+	if next != nil {
 		branch := params.Clone()
 		branch.Index++
-		branch.Node = branch.Node.Children[char]
+		branch.Node = next
 		branch.Path = append(branch.Path, char)
 		branch.DepthIncrement = 0
 		branch.NumEditsIncrement = 0
@@ -104,8 +76,7 @@ func (fmc *FuzzyMatcherCore[T]) Recurse(params ft.RecurseParameters) []ft.MatchC
 		if params.Index+1 <= len(params.Word) {
 			branch := params.Clone()
 			branch.Index++
-			branch.DepthIncrement = 1
-			branch.NumEditsIncrement = 1
+			fmc.applyEdit(&branch, ft.EditSkip, params.Index, string(char), "")
 
 			result := fmc.Recurse(branch)
 
@@ -117,59 +88,83 @@ func (fmc *FuzzyMatcherCore[T]) Recurse(params ft.RecurseParameters) []ft.MatchC
 		// 4.2. 
 		matches = append(matches, fmc.BreadthFirstSearch(params.Clone())...)
 
-		// 5.
-		if fmc.CoreParams.CorrectOcrMisreads {
-			// 5.1
-			for _, sub := range ocrMisreads[char] {
-				if params.Node.Children[sub] != nil {
-					branch := params.Clone()	
-					branch.Index++
-					branch.Node = branch.Node.Children[sub]
-					branch.Path = append(branch.Path, sub)
-					branch.DepthIncrement = 1
-					branch.NumEditsIncrement = 1
+		// 4.3.
+		if params.Index+1 < len(params.Word) && params.Word[params.Index+1] != char {
+			nextChar := params.Word[params.Index+1]
 
-					result := fmc.Recurse(branch)
+			if swapped := params.Node.Child(nextChar); swapped != nil {
+				if swapped = swapped.Child(char); swapped != nil {
+					branch := params.Clone()
+					branch.Index += 2
+					branch.Node = swapped
+					branch.Path = append(branch.Path, nextChar, char)
+					fmc.applyEdit(&branch, ft.EditTransposition, params.Index, string([]rune{char, nextChar}), string([]rune{nextChar, char}))
 
-					if result != nil {
-						matches = append(matches, result...)
-					}
+					matches = append(matches, fmc.Recurse(branch)...)
 				}
 			}
+		}
+
+		if params.NumEdits < float64(params.MaxEdits) && params.Depth < params.MaxDepth {
+			params.Node.EachChild(func(child *ft.FuzzyMatcherNode) bool {
+				// 4.4.
+				if child.Child(char) != nil && params.Unexplored(child, params.Index, params.NumEdits+fmc.editCost(ft.EditInsertion, "", string(child.Char)), params.Depth+1) {
+					branch := params.Clone()
+					branch.Node = child
+					branch.Path = append(branch.Path, child.Char)
+					fmc.applyEdit(&branch, ft.EditInsertion, params.Index, "", string(child.Char))
 
-			// 5.2
-			if params.Index+1 < len(params.Word) {
-				twoChars := string(params.Word[params.Index : params.Index+2])
-
-				if multiCharMisreads[twoChars] != nil {
-					for _, subRunes := range multiCharMisreads[twoChars] {
-						child := params.Node
-						valid := true
-						for _, r := range subRunes {
-							next := child.Children[r]
-							if next == nil {
-								valid = false
-								break
-							}
-							child = next
-						}
-						if valid {
-							branch := params.Clone()
-							branch.Index += len(twoChars)
-							branch.Node = child
-							branch.Path = append(branch.Path, subRunes...)
-							branch.DepthIncrement = 1
-							branch.NumEditsIncrement = 1
-
-							result := fmc.Recurse(branch)
-
-							matches = append(matches, result...)
-						}
+					matches = append(matches, fmc.Recurse(branch)...)
+				}
+
+				// 4.5.
+				// Matching characters are already followed by 3.
+				if child.Char != char && resumes(child, params.Word, params.Index+1) && params.Unexplored(child, params.Index+1, params.NumEdits+fmc.editCost(ft.EditSubstitution, string(char), string(child.Char)), params.Depth+1) {
+					branch := params.Clone()
+					branch.Index++
+					branch.Node = child
+					branch.Path = append(branch.Path, child.Char)
+					fmc.applyEdit(&branch, ft.EditSubstitution, params.Index, string(char), string(child.Char))
+
+					matches = append(matches, fmc.Recurse(branch)...)
+				}
+
+				return true
+			})
+		}
+
+		// 5.
+		if fmc.CoreParams.CorrectOcrMisreads {
+			for _, confusion := range fmc.ocrConfusions[char] {
+				if !confusion.matches(params.Word, params.Index) {
+					continue
+				}
+
+				child := params.Node
+				for _, r := range confusion.to {
+					if child = child.Child(r); child == nil {
+						break
 					}
 				}
+				if child == nil {
+					continue
+				}
+
+				branch := params.Clone()
+				branch.Index += len(confusion.from)
+				branch.Node = child
+				branch.Path = append(branch.Path, confusion.to...)
+				fmc.applyEdit(&branch, confusion.operation(), params.Index, string(confusion.from), string(confusion.to))
+
+				matches = append(matches, fmc.Recurse(branch)...)
 			}
 		}
 	}
 
 	return matches
 }
+
+// Returns true if the search word can carry on from node at index, either because it ends there or the next character is a child
+func resumes(node *ft.FuzzyMatcherNode, word []rune, index int) bool {
+	return index >= len(word) || node.Child(word[index]) != nil
+}
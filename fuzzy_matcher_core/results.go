@@ -0,0 +1,78 @@
+package fuzzymatchercore
+
+import (
+	"sort"
+
+	ft "github.com/oiamo123/fuzzy_matcher/fuzzy_types"
+)
+
+// rankedMatch carries the values used to order a match before it is returned
+type rankedMatch[T ft.FuzzyMatcherDataSource] struct {
+	ID    int
+	Edits float64
+	Match ft.FuzzyMatch[T]
+}
+
+/*
+RANK FLOW
+1. Resolve the search options, falling back to the defaults
+2. Sort by score, breaking ties with the configured policy
+3. Apply the offset and limit
+*/
+
+// Resolves the options passed to a search, only the first set of options is used
+func resolveSearchOptions(opts []ft.SearchOptions) ft.SearchOptions {
+	options := ft.SearchOptions{}
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+
+	if options.Limit == 0 {
+		options.Limit = DefaultResultLimit
+	}
+
+	if options.Offset < 0 {
+		options.Offset = 0
+	}
+
+	return options
+}
+
+// Sorts the matches and returns the requested page
+func (fmc *FuzzyMatcherCore[T]) rankMatches(matches []rankedMatch[T], options ft.SearchOptions) []ft.FuzzyMatch[T] {
+	// 2.
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.Match.Score != b.Match.Score {
+			return a.Match.Score > b.Match.Score
+		}
+
+		switch options.TieBreak {
+		case ft.TieBreakIDDesc:
+			return a.ID > b.ID
+		case ft.TieBreakEdits:
+			if a.Edits != b.Edits {
+				return a.Edits < b.Edits
+			}
+		}
+
+		return a.ID < b.ID
+	})
+
+	// 3.
+	if options.Offset >= len(matches) {
+		return nil
+	}
+
+	matches = matches[options.Offset:]
+	if options.Limit > 0 && len(matches) > options.Limit {
+		matches = matches[:options.Limit]
+	}
+
+	page := make([]ft.FuzzyMatch[T], 0, len(matches))
+	for _, match := range matches {
+		page = append(page, match.Match)
+	}
+
+	return page
+}
@@ -0,0 +1,89 @@
+package fuzzymatchercore
+
+import (
+	"sync"
+
+	ft "github.com/oiamo123/fuzzy_matcher/fuzzy_types"
+)
+
+// Scratch maps that grew past this many entries are dropped rather than pooled
+const maxPooledScratchEntries = 4096
+
+// searchScratch holds the maps used to merge field results, pooled so repeated searches reuse them
+type searchScratch struct {
+	allResults          map[ft.Field][]ft.MatchCandidate
+	matchedEntries      map[int]map[ft.Field]string
+	matchedEntriesCount map[int]map[ft.Field]float64
+	matchedSynonyms     map[int]map[ft.Field]string
+
+	// Inner maps released by previous searches
+	freeStrings []map[ft.Field]string
+	freeCounts  []map[ft.Field]float64
+}
+
+var searchScratchPool = sync.Pool{
+	New: func() any {
+		return &searchScratch{
+			allResults:          make(map[ft.Field][]ft.MatchCandidate),
+			matchedEntries:      make(map[int]map[ft.Field]string),
+			matchedEntriesCount: make(map[int]map[ft.Field]float64),
+			matchedSynonyms:     make(map[int]map[ft.Field]string),
+		}
+	},
+}
+
+// Gets scratch maps for a search, they must be released once the search no longer references them
+func getSearchScratch() *searchScratch {
+	return searchScratchPool.Get().(*searchScratch)
+}
+
+// Returns an empty field to string map
+func (s *searchScratch) fieldStrings() map[ft.Field]string {
+	if n := len(s.freeStrings); n > 0 {
+		m := s.freeStrings[n-1]
+		s.freeStrings = s.freeStrings[:n-1]
+		return m
+	}
+
+	return make(map[ft.Field]string)
+}
+
+// Returns an empty field to count map
+func (s *searchScratch) fieldCounts() map[ft.Field]float64 {
+	if n := len(s.freeCounts); n > 0 {
+		m := s.freeCounts[n-1]
+		s.freeCounts = s.freeCounts[:n-1]
+		return m
+	}
+
+	return make(map[ft.Field]float64)
+}
+
+// Clears the scratch maps and puts them back in the pool
+func (s *searchScratch) release() {
+	if len(s.matchedEntries) > maxPooledScratchEntries {
+		return
+	}
+
+	for id, m := range s.matchedEntries {
+		clear(m)
+		s.freeStrings = append(s.freeStrings, m)
+		delete(s.matchedEntries, id)
+	}
+
+	for id, m := range s.matchedSynonyms {
+		clear(m)
+		s.freeStrings = append(s.freeStrings, m)
+		delete(s.matchedSynonyms, id)
+	}
+
+	for id, m := range s.matchedEntriesCount {
+		clear(m)
+		s.freeCounts = append(s.freeCounts, m)
+		delete(s.matchedEntriesCount, id)
+	}
+
+	clear(s.allResults)
+
+	searchScratchPool.Put(s)
+}
@@ -0,0 +1,87 @@
+package fuzzymatchercore
+
+import (
+	"slices"
+	"unsafe"
+
+	ft "github.com/oiamo123/fuzzy_matcher/fuzzy_types"
+)
+
+/*
+STATS FLOW
+1. Walk the trie depth first, keeping the path to each node
+2. Count each node under the key it belongs to, nodes before the ':' are shared by the whole key
+3. Record the depth of each indexed value and how many children each node has
+4. Add up the estimated size of the nodes, the reverse index and the expiry heap
+*/
+
+// Returns the size and shape of the trie
+func (fmc *FuzzyMatcherCore[T]) Stats() ft.IndexStats {
+	mu := fmc.lock()
+	mu.RLock()
+	defer mu.RUnlock()
+
+	stats := ft.IndexStats{
+		NodesByKey:         make(map[string]int),
+		BranchingHistogram: make(map[int]int),
+		Entries:            len(fmc.Entries),
+		PendingExpiries:    fmc.ExpiryHeap.Len(),
+	}
+
+	if fmc.Root == nil {
+		return stats
+	}
+
+	// 1.
+	totalDepth := 0
+	var walk func(node *ft.FuzzyMatcherNode, path []rune)
+	walk = func(node *ft.FuzzyMatcherNode, path []rune) {
+		path = append(path, node.Label()...)
+
+		stats.Nodes++
+		stats.EstimatedBytes += node.EstimatedBytes()
+		stats.BranchingHistogram[node.NumChildren()]++
+
+		// 2.
+		colon := slices.Index(path, ':')
+
+		if node.Parent != nil {
+			if colon >= 0 && colon < len(path)-1 {
+				stats.NodesByKey[string(path[:colon])]++
+			} else {
+				stats.KeyNodes++
+			}
+		}
+
+		// 3.
+		if numIDs := node.NumIDs(); numIDs > 0 {
+			stats.EndOfStringNodes++
+			stats.IDs += numIDs
+
+			depth := len(path) - colon - 1
+			totalDepth += depth
+			if depth > stats.MaxDepth {
+				stats.MaxDepth = depth
+			}
+		}
+
+		node.EachEdge(func(child *ft.FuzzyMatcherNode) {
+			walk(child, path)
+		})
+	}
+	walk(fmc.Root, nil)
+
+	if stats.EndOfStringNodes > 0 {
+		stats.AverageDepth = float64(totalDepth) / float64(stats.EndOfStringNodes)
+	}
+
+	// 4.
+	nodeSize := int64(unsafe.Sizeof(fmc.Root))
+	stats.EstimatedBytes += ft.EstimateMapBytes(len(fmc.idNodes), int64(unsafe.Sizeof(0)+unsafe.Sizeof([]*ft.FuzzyMatcherNode{})))
+	for _, nodes := range fmc.idNodes {
+		stats.EstimatedBytes += int64(cap(nodes)) * nodeSize
+	}
+	stats.EstimatedBytes += int64(cap(fmc.ExpiryHeap)) * int64(unsafe.Sizeof(ft.ExpiryEntry{}))
+
+	return stats
+}
@@ -0,0 +1,160 @@
+package fuzzymatchercore
+
+import (
+	"sort"
+
+	ft "github.com/oiamo123/fuzzy_matcher/fuzzy_types"
+)
+
+// symSpellIndex is a symmetric delete index over a field's values
+// Every value is stored under each string left after deleting up to depth of its characters,
+// so a value within depth edits of the query shares at least one of those strings with the query's own deletes
+type symSpellIndex struct {
+	maxEdits int     // Edit budget the index is built for, in the same units as MaxEdits
+	depth    int     // Edits that fit in maxEdits at the cheapest cost, each needs a delete on either side
+	cheapest float64 // Least any edit editDistance counts costs
+	costs    ft.EditCosts
+	values   map[string]map[int]bool // IDs holding each value
+	deletes  map[string][]string     // Values stored under each delete
+}
+
+func newSymSpellIndex(maxEdits int, costs ft.EditCosts) *symSpellIndex {
+	cheapest := editDistanceCheapest(costs)
+	return &symSpellIndex{
+		maxEdits: maxEdits,
+		depth:    editsWithin(maxEdits, cheapest),
+		cheapest: cheapest,
+		costs:    costs,
+		values:   make(map[string]map[int]bool),
+		deletes:  make(map[string][]string),
+	}
+}
+
+// Adds an ID to a value, indexing the value's deletes the first time it's seen
+func (s *symSpellIndex) Insert(value string, id int) {
+	ids, ok := s.values[value]
+	if !ok {
+		ids = make(map[int]bool)
+		s.values[value] = ids
+
+		for _, deleted := range symSpellDeletes(value, s.depth) {
+			s.deletes[deleted] = append(s.deletes[deleted], value)
+		}
+	}
+
+	ids[id] = true
+}
+
+// Removes an ID from a value, dropping the value's deletes once it has no IDs left
+func (s *symSpellIndex) Remove(value string, id int) {
+	ids, ok := s.values[value]
+	if !ok {
+		return
+	}
+
+	delete(ids, id)
+	if len(ids) > 0 {
+		return
+	}
+
+	delete(s.values, value)
+	for _, deleted := range symSpellDeletes(value, s.depth) {
+		values := s.deletes[deleted]
+		for i, stored := range values {
+			if stored == value {
+				values[i] = values[len(values)-1]
+				values = values[:len(values)-1]
+				break
+			}
+		}
+
+		if len(values) == 0 {
+			delete(s.deletes, deleted)
+		} else {
+			s.deletes[deleted] = values
+		}
+	}
+}
+
+/*
+SYMSPELL SEARCH FLOW
+1. Delete as many characters from the query value as edits fit in MaxEdits at the cheapest cost
+	- Searches allowing more than the index was built for are capped to it
+2. Collect the values stored under any of the query's deletes
+3. Work out the cost of each value with the core's edit costs, keeping the ones within MaxEdits
+*/
+
+// Finds the values within params.MaxEdits of the query value, safe to call under the read lock
+func (s *symSpellIndex) Search(params ft.RecurseParameters) []ft.MatchCandidate {
+	word := params.Word[len(params.Key)+1:]
+
+	// 1.
+	depth := editsWithin(min(params.MaxEdits, s.maxEdits), s.cheapest)
+
+	// 2.
+	candidates := map[string]bool{}
+	for _, deleted := range symSpellDeletes(string(word), depth) {
+		for _, value := range s.deletes[deleted] {
+			candidates[value] = true
+		}
+	}
+
+	values := make([]string, 0, len(candidates))
+	for value := range candidates {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+
+	// 3.
+	matches := []ft.MatchCandidate{}
+	for _, value := range values {
+		if !params.Budget.Visit() {
+			break
+		}
+
+		cost, edits := editDistance(s.costs, word, []rune(value), params.Explain)
+		if ft.ExceedsEdits(cost, params.MaxEdits) {
+			continue
+		}
+
+		ids := make([]int, 0, len(s.values[value]))
+		for id := range s.values[value] {
+			ids = append(ids, id)
+		}
+		sort.Ints(ids)
+
+		matches = append(matches, ft.MatchCandidate{
+			Text:      string(params.Key) + ":" + value,
+			EditCount: cost,
+			ID:        ids,
+			Edits:     edits,
+		})
+	}
+
+	return matches
+}
+
+// Returns the value and every distinct string left after deleting up to maxEdits of its characters
+func symSpellDeletes(value string, maxEdits int) []string {
+	seen := map[string]bool{value: true}
+	deletes := []string{value}
+
+	current := []string{value}
+	for edits := 0; edits < maxEdits; edits++ {
+		next := []string{}
+		for _, word := range current {
+			runes := []rune(word)
+			for i := range runes {
+				deleted := string(runes[:i]) + string(runes[i+1:])
+				if !seen[deleted] {
+					seen[deleted] = true
+					deletes = append(deletes, deleted)
+					next = append(next, deleted)
+				}
+			}
+		}
+		current = next
+	}
+
+	return deletes
+}
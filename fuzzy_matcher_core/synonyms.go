@@ -0,0 +1,114 @@
+package fuzzymatchercore
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	ft "github.com/oiamo123/fuzzy_matcher/fuzzy_types"
+)
+
+// Loads a synonym dictionary from JSON, e.g. {"ben": ["benjamin", "benedict"], "mike": ["michael"]}
+// Values are lowercased and trimmed
+func LoadSynonymDictionary(r io.Reader) (ft.SynonymDictionary, error) {
+	raw := map[string][]string{}
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("decoding synonym dictionary: %w", err)
+	}
+
+	dictionary := make(ft.SynonymDictionary, len(raw))
+	for value, variants := range raw {
+		value = strings.ToLower(strings.TrimSpace(value))
+		if value == "" {
+			continue
+		}
+
+		for _, variant := range variants {
+			variant = strings.ToLower(strings.TrimSpace(variant))
+			if variant != "" && variant != value {
+				dictionary[value] = append(dictionary[value], variant)
+			}
+		}
+	}
+
+	return dictionary, nil
+}
+
+// Loads a synonym dictionary from a JSON file
+func LoadSynonymDictionaryFile(path string) (ft.SynonymDictionary, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return LoadSynonymDictionary(file)
+}
+
+// Returns the synonym score to use, falling back to the default when none is set
+func (fmc *FuzzyMatcherCore[T]) synonymScore() float64 {
+	if fmc.CoreParams.SynonymScore > 0 {
+		return fmc.CoreParams.SynonymScore
+	}
+
+	return DefaultSynonymScore
+}
+
+// Returns the normalized variants of a normalized field value, excluding the value itself
+func (fmc *FuzzyMatcherCore[T]) expandSynonyms(key ft.Field, normalized string) []string {
+	dictionary, ok := fmc.CoreParams.Synonyms[key]
+	if !ok {
+		return nil
+	}
+
+	variants := []string{}
+	seen := map[string]bool{normalized: true}
+	for _, variant := range dictionary[normalized] {
+		variant = fmc.NormalizeFieldFor(key, variant)
+		if variant == "" || seen[variant] {
+			continue
+		}
+
+		seen[variant] = true
+		variants = append(variants, variant)
+	}
+
+	return variants
+}
+
+/*
+SYNONYM SEARCH FLOW
+1. Expand the query value into its variants using the field's dictionary
+2. Search the trie for each variant with the same limits and options as the query value
+3. Mark each match with the variant it was found through
+*/
+
+// Finds the entries matching a synonym of the query value, the caller must hold the lock
+// query is the query value's recurse parameters, its explain flag, budget and max matches are shared with each variant
+func (fmc *FuzzyMatcherCore[T]) SearchSynonyms(query ft.RecurseParameters, parameters ft.FuzzyMatcherParameters) []ft.MatchCandidate {
+	key := ft.Field(query.Key)
+	normalized := string(query.Word[len(query.Key)+1:])
+	matches := []ft.MatchCandidate{}
+
+	// 1.
+	for _, variant := range fmc.expandSynonyms(key, normalized) {
+		// 2.
+		recurseParameters := fmc.NewRecurseParameters(key, variant, parameters)
+		recurseParameters.Explain = query.Explain
+		recurseParameters.Budget = query.Budget
+		recurseParameters.MaxMatches = query.MaxMatches
+
+		result := fmc.searchValue(recurseParameters)
+
+		// 3.
+		for i := range result {
+			result[i].Synonym = variant
+		}
+
+		matches = append(matches, result...)
+	}
+
+	return matches
+}
@@ -0,0 +1,167 @@
+package fuzzymatchercore
+
+import (
+	"container/heap"
+	"fmt"
+	"time"
+
+	ft "github.com/oiamo123/fuzzy_matcher/fuzzy_types"
+)
+
+/*
+UPSERT FLOW
+0. Check every entry has an expiry when expiration is used, before anything is changed
+1. Work out the search strings of the stored entry and the new entry
+2. Remove the stored entry's search strings that the new entry doesn't have
+3. Insert the new entry's search strings, Insert leaves the ones already pointing at the ID alone
+4. Replace the stored entry
+5. Schedule the expiries of every upserted ID
+	- An ID keeping its expiry only needs expiries for its new search strings
+	- An ID with a new expiry needs them for all of its search strings, the old ones are left in the heap for Clean to skip
+*/
+
+// Inserts entries, replacing any stored entry with the same ID
+// Only the field values that changed are removed from and inserted into the trie
+func (fmc *FuzzyMatcherCore[T]) Upsert(entries []T) error {
+	mu := fmc.lock()
+	mu.Lock()
+	defer mu.Unlock()
+
+	if err := fmc.prepare(); err != nil {
+		return err
+	}
+
+	// 0.
+	fuzzyEntries := make([]*ft.FuzzyEntry, len(entries))
+	for i, entry := range entries {
+		fuzzyEntries[i] = entry.CreateFuzzyEntry()
+
+		if fmc.CoreParams.UseExpiration && fuzzyEntries[i].Expiry.IsZero() {
+			return fmt.Errorf("UseExpiration set to true. Cannot insert entry with no expiry: %v", entry)
+		}
+	}
+
+	expiries := make(map[int]time.Time)
+	searchStringsByID := make(map[int][]string)
+
+	for i, entry := range entries {
+		fuzzyEntry := fuzzyEntries[i]
+
+		// 1.
+		newStrings := fmc.entrySearchStrings(fuzzyEntry)
+
+		oldStrings := map[string]bool{}
+		if stored, ok := fmc.Entries[fuzzyEntry.ID]; ok {
+			for _, searchString := range fmc.entrySearchStrings(stored.CreateFuzzyEntry()) {
+				oldStrings[searchString] = true
+			}
+		}
+
+		scheduled, ok := fmc.expiries[fuzzyEntry.ID]
+		sameExpiry := ok && scheduled.Equal(fuzzyEntry.Expiry)
+		if fmc.CoreParams.UseExpiration && !sameExpiry {
+			fmc.staleExpiries += len(fmc.idNodes[fuzzyEntry.ID])
+		}
+
+		// 2.
+		keep := make(map[string]bool, len(newStrings))
+		for _, searchString := range newStrings {
+			keep[searchString] = true
+		}
+
+		for searchString := range oldStrings {
+			if !keep[searchString] && fmc.removePath(searchString, fuzzyEntry.ID) && sameExpiry {
+				fmc.staleExpiries++
+			}
+		}
+
+		// 3.
+		for _, searchString := range newStrings {
+			fmc.Insert(searchString, fuzzyEntry.ID).IsEndofString = true
+		}
+
+		// 4.
+		fmc.Entries[fuzzyEntry.ID] = entry
+		expiries[fuzzyEntry.ID] = fuzzyEntry.Expiry
+		for _, searchString := range newStrings {
+			if !sameExpiry || !oldStrings[searchString] {
+				searchStringsByID[fuzzyEntry.ID] = append(searchStringsByID[fuzzyEntry.ID], searchString)
+			}
+		}
+	}
+
+	// 5.
+	if fmc.CoreParams.UseExpiration && len(expiries) > 0 {
+		fmc.scheduleExpiries(expiries, searchStringsByID)
+		fmc.compactExpiries()
+	}
+
+	return nil
+}
+
+// Returns every search string for an entry's fields, including sound codes
+func (fmc *FuzzyMatcherCore[T]) entrySearchStrings(fuzzyEntry *ft.FuzzyEntry) []string {
+	searchStrings := []string{}
+	for key, field := range fuzzyEntry.Key {
+		searchStrings = append(searchStrings, fmc.searchStrings(key, fmc.NormalizeFieldFor(key, field))...)
+	}
+
+	return searchStrings
+}
+
+// Schedules the expiries of the given IDs on the nodes of their search strings
+// Expiries left in the heap for any other time are stale, Clean skips them
+// The caller must hold the write lock
+func (fmc *FuzzyMatcherCore[T]) scheduleExpiries(expiries map[int]time.Time, searchStringsByID map[int][]string) {
+	for id, expiry := range expiries {
+		fmc.expiries[id] = expiry
+
+		for _, searchString := range searchStringsByID[id] {
+			heap.Push(&fmc.ExpiryHeap, ft.ExpiryEntry{
+				Node:   fmc.findNode(searchString),
+				Expiry: expiry,
+				ID:     id,
+			})
+		}
+	}
+}
+
+// Drops the scheduled expiry of an ID, its expiries are left in the heap for Clean to skip
+// Takes as long as the ID has fields, the heap is only rebuilt by compactExpiries
+// The caller must hold the write lock
+func (fmc *FuzzyMatcherCore[T]) dropExpiries(id int) {
+	if _, ok := fmc.expiries[id]; !ok {
+		return
+	}
+
+	fmc.staleExpiries += len(fmc.idNodes[id])
+	delete(fmc.expiries, id)
+}
+
+// Returns true if an expiry in the heap is still due, rather than left behind by a removal or upsert
+// The caller must hold a lock
+func (fmc *FuzzyMatcherCore[T]) scheduled(expiry ft.ExpiryEntry) bool {
+	at, ok := fmc.expiries[expiry.ID]
+	return ok && at.Equal(expiry.Expiry) && expiry.Node.HasID(expiry.ID)
+}
+
+// Rebuilds the expiry heap without its stale expiries once they make up half of it
+// Each rebuild is paid for by the removals and upserts that left the stale expiries behind
+// The caller must hold the write lock
+func (fmc *FuzzyMatcherCore[T]) compactExpiries() {
+	if fmc.staleExpiries == 0 || fmc.staleExpiries*2 < fmc.ExpiryHeap.Len() {
+		return
+	}
+
+	kept := fmc.ExpiryHeap[:0]
+	for _, expiry := range fmc.ExpiryHeap {
+		if fmc.scheduled(expiry) {
+			kept = append(kept, expiry)
+		}
+	}
+
+	clear(fmc.ExpiryHeap[len(kept):])
+	fmc.ExpiryHeap = kept
+	heap.Init(&fmc.ExpiryHeap)
+	fmc.staleExpiries = 0
+}
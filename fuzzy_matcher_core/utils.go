@@ -1,6 +1,8 @@
 package fuzzymatchercore
 
 import (
+	"math"
+
 	ft "github.com/oiamo123/fuzzy_matcher/fuzzy_types"
 )
 
@@ -9,7 +11,7 @@ CHECKS FLOW
 1. Increment depth and num edits
 2. Check if node has been visited and update as needed
 3. Check if current node is end of string
-4. Check if we've exceeded limits
+4. Check if we've exceeded limits or the search budget
 */
 
 func (fmc *FuzzyMatcherCore[T]) ProcessNode(params *ft.RecurseParameters) ([]ft.MatchCandidate, bool) {
@@ -26,27 +28,56 @@ func (fmc *FuzzyMatcherCore[T]) ProcessNode(params *ft.RecurseParameters) ([]ft.
 
     // 3. If this node is an end-of-string, add match
     if params.Node.IsEndofString {
-        ids := make([]int, 0, len(params.Node.ID))
-        for id := range params.Node.ID {
-            ids = append(ids, id)
-        }
-
         matches = append(matches, ft.MatchCandidate{
             Text:        string(params.Path),
             EditCount:   params.NumEdits,
             SearchDepth: params.Depth,
-            ID:          ids,
+            ID:          params.Node.IDs(),
+            Edits:       params.Edits,
         })
     }
 
     // 4. Early exit if over limits
-    if params.NumEdits > params.MaxEdits || params.Depth > params.MaxDepth {
+    if ft.ExceedsEdits(params.NumEdits, params.MaxEdits) || params.Depth > params.MaxDepth {
         return matches, false // stop further recursion/BFS
     }
 
+    // Early exit if the search was cancelled or visited too many nodes
+    if !params.Budget.Visit() {
+        return matches, false
+    }
+
     return matches, true // continue exploring
 }
 
+// Returns what an edit costs with the core's edit costs
+func (fmc *FuzzyMatcherCore[T]) editCost(operation ft.EditOperation, from, to string) float64 {
+	return fmc.CoreParams.EditCosts.Cost(operation, from, to)
+}
+
+// Charges an edit to a branch and records it when the search is being explained
+// index is the position in the search word, it is stored relative to the start of the field value
+func (fmc *FuzzyMatcherCore[T]) applyEdit(branch *ft.RecurseParameters, operation ft.EditOperation, index int, from, to string) {
+	branch.DepthIncrement = 1
+	branch.NumEditsIncrement = fmc.editCost(operation, from, to)
+	fmc.recordEdit(branch, operation, index, from, to)
+}
+
+// Records an edit on a branch when the search is being explained
+// index is the position in the search word, it is stored relative to the start of the field value
+func (fmc *FuzzyMatcherCore[T]) recordEdit(branch *ft.RecurseParameters, operation ft.EditOperation, index int, from, to string) {
+	if !branch.Explain {
+		return
+	}
+
+	branch.Edits = append(branch.Edits, ft.Edit{
+		Operation: operation,
+		Position:  index - len(branch.Key) - 1,
+		From:      from,
+		To:        to,
+	})
+}
+
 /*
 COMPUTES SCORE
 - Uses next character prediction + distance to calculate similarity
@@ -62,15 +93,16 @@ func (fmc *FuzzyMatcherCore[T]) ComputeScore(
 	s1 := path[len(key)+1:]
 	s2 := word[len(key)+1:]
 
-	distance := fmc.CalculateSimilarity(string(s1), string(s2), method)
+	distance := fmc.calculateSimilarity(string(s1), string(s2), method)
 
     return float64(predictedChar*0.4) + float64(distance*0.6)
 }
 
-func (fmc *FuzzyMatcherCore[T]) MakeKey(index, edits, depth, nodeID int) ft.VisitKey {
+// Edits are keyed in thousandths so fractional costs like 0.5 and 0 don't share a key
+func (fmc *FuzzyMatcherCore[T]) MakeKey(index int, edits float64, depth, nodeID int) ft.VisitKey {
 	return ft.VisitKey(
 		(uint64(index) << 48) |
-        (uint64(edits) << 32) |
+        ((uint64(math.Round(edits*1000)) & 0xFFFF) << 32) |
         (uint64(depth) << 16) |
         uint64(nodeID & 0xFFFF),
 	)
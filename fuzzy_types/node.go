@@ -0,0 +1,413 @@
+package fuzzymatchertypes
+
+import (
+	"sort"
+	"unsafe"
+)
+
+// NodeLayout selects how trie nodes store their children and IDs
+type NodeLayout string
+
+// Node layouts
+const (
+	MapLayout     NodeLayout = ""        // One node per rune, children and IDs kept in maps
+	CompactLayout NodeLayout = "compact" // Path compressed edges, children and IDs kept in sorted slices
+)
+
+// compactNode holds a compact layout node's children, IDs and edge label
+// Nodes using the map layout leave it nil
+type compactNode struct {
+	children []*FuzzyMatcherNode // Sorted by the first rune of their labels
+	ids      []int               // Sorted
+	label    []rune              // Runes on the edge from the parent, ending with the node's Char
+	edge     *FuzzyMatcherNode   // Only set on positions inside an edge, the node the edge leads to
+}
+
+// Creates an empty root node using the given layout
+func NewRootNode(layout NodeLayout) *FuzzyMatcherNode {
+	if layout == CompactLayout {
+		return &FuzzyMatcherNode{compact: &compactNode{}}
+	}
+
+	return &FuzzyMatcherNode{Children: make(map[rune]*FuzzyMatcherNode)}
+}
+
+// Creates a node using the given layout, label holds the runes on the edge from parent and must not be empty
+// The map layout only supports single rune labels
+func NewNode(layout NodeLayout, label []rune, parent *FuzzyMatcherNode) *FuzzyMatcherNode {
+	node := &FuzzyMatcherNode{Char: label[len(label)-1], Parent: parent}
+	if layout == CompactLayout {
+		node.compact = &compactNode{label: label}
+	} else {
+		node.Children = make(map[rune]*FuzzyMatcherNode)
+	}
+
+	return node
+}
+
+// NodePosition identifies a place in the trie
+// Positions inside a compressed edge are new nodes each time they're reached, so they're identified by the edge and offset instead
+type NodePosition struct {
+	node   *FuzzyMatcherNode
+	offset int
+}
+
+// Returns the node's place in the trie
+func (n *FuzzyMatcherNode) Position() NodePosition {
+	if n.compact != nil && n.compact.edge != nil {
+		return NodePosition{node: n.compact.edge, offset: len(n.compact.label)}
+	}
+
+	return NodePosition{node: n}
+}
+
+// Returns the layout the node uses
+func (n *FuzzyMatcherNode) Layout() NodeLayout {
+	if n.compact != nil {
+		return CompactLayout
+	}
+
+	return MapLayout
+}
+
+// Returns the runes on the edge from the node's parent, ending with its Char
+// The root's label is empty
+func (n *FuzzyMatcherNode) Label() []rune {
+	if n.compact != nil {
+		return n.compact.label
+	}
+
+	if n.Parent == nil {
+		return nil
+	}
+
+	return []rune{n.Char}
+}
+
+// Returns the child reached by c, nil if there is none
+// In the compact layout, positions inside a compressed edge are returned as temporary nodes that can be searched from but not modified
+func (n *FuzzyMatcherNode) Child(c rune) *FuzzyMatcherNode {
+	if n.compact == nil {
+		return n.Children[c]
+	}
+
+	// Inside an edge there is only one way forward
+	if edge := n.compact.edge; edge != nil {
+		offset := len(n.compact.label)
+		if edge.compact.label[offset] != c {
+			return nil
+		}
+
+		return edge.position(offset, n)
+	}
+
+	i, ok := n.childIndex(c)
+	if !ok {
+		return nil
+	}
+
+	return n.compact.children[i].position(0, n)
+}
+
+// Calls fn with each child reached by a single rune until fn returns false, see Child
+func (n *FuzzyMatcherNode) EachChild(fn func(child *FuzzyMatcherNode) bool) {
+	if n.compact == nil {
+		for _, child := range n.Children {
+			if !fn(child) {
+				return
+			}
+		}
+		return
+	}
+
+	if edge := n.compact.edge; edge != nil {
+		fn(edge.position(len(n.compact.label), n))
+		return
+	}
+
+	for _, child := range n.compact.children {
+		if !fn(child.position(0, n)) {
+			return
+		}
+	}
+}
+
+// Calls fn with each child node stored under the node, compressed edges are not split into positions
+func (n *FuzzyMatcherNode) EachEdge(fn func(child *FuzzyMatcherNode)) {
+	if n.compact == nil {
+		for _, child := range n.Children {
+			fn(child)
+		}
+		return
+	}
+
+	for _, child := range n.compact.children {
+		fn(child)
+	}
+}
+
+// Returns the number of child nodes stored under the node
+func (n *FuzzyMatcherNode) NumChildren() int {
+	if n.compact == nil {
+		return len(n.Children)
+	}
+
+	return len(n.compact.children)
+}
+
+// Removes a child node stored under the node
+func (n *FuzzyMatcherNode) RemoveChild(child *FuzzyMatcherNode) {
+	if n.compact == nil {
+		if n.Children[child.Char] == child {
+			delete(n.Children, child.Char)
+		}
+		return
+	}
+
+	i, ok := n.childIndex(child.compact.label[0])
+	if ok && n.compact.children[i] == child {
+		n.compact.children = append(n.compact.children[:i], n.compact.children[i+1:]...)
+	}
+}
+
+// Returns true if the node holds the ID
+func (n *FuzzyMatcherNode) HasID(id int) bool {
+	if n.compact == nil {
+		return n.ID[id]
+	}
+
+	i := sort.SearchInts(n.compact.ids, id)
+	return i < len(n.compact.ids) && n.compact.ids[i] == id
+}
+
+// Returns the number of IDs the node holds
+func (n *FuzzyMatcherNode) NumIDs() int {
+	if n.compact == nil {
+		return len(n.ID)
+	}
+
+	return len(n.compact.ids)
+}
+
+// Returns a copy of the IDs the node holds, sorted in the compact layout
+func (n *FuzzyMatcherNode) IDs() []int {
+	if n.compact != nil {
+		ids := make([]int, len(n.compact.ids))
+		copy(ids, n.compact.ids)
+		return ids
+	}
+
+	ids := make([]int, 0, len(n.ID))
+	for id := range n.ID {
+		ids = append(ids, id)
+	}
+
+	return ids
+}
+
+// Adds an ID to the node, returns false if the node already held it
+func (n *FuzzyMatcherNode) AddID(id int) bool {
+	if n.compact == nil {
+		if n.ID == nil {
+			n.ID = make(map[int]bool)
+		}
+		if n.ID[id] {
+			return false
+		}
+
+		n.ID[id] = true
+		return true
+	}
+
+	ids := n.compact.ids
+	i := sort.SearchInts(ids, id)
+	if i < len(ids) && ids[i] == id {
+		return false
+	}
+
+	ids = append(ids, 0)
+	copy(ids[i+1:], ids[i:])
+	ids[i] = id
+	n.compact.ids = ids
+
+	return true
+}
+
+// Removes an ID from the node, returns false if the node didn't hold it
+func (n *FuzzyMatcherNode) RemoveID(id int) bool {
+	if n.compact == nil {
+		if !n.ID[id] {
+			return false
+		}
+
+		delete(n.ID, id)
+		return true
+	}
+
+	ids := n.compact.ids
+	i := sort.SearchInts(ids, id)
+	if i == len(ids) || ids[i] != id {
+		return false
+	}
+
+	n.compact.ids = append(ids[:i], ids[i+1:]...)
+	if len(n.compact.ids) == 0 {
+		n.compact.ids = nil
+	}
+
+	return true
+}
+
+// Adds a compact child node under the node, replacing any child whose label starts with the same rune
+func (n *FuzzyMatcherNode) AddChild(child *FuzzyMatcherNode) {
+	if n.compact == nil {
+		n.Children[child.Char] = child
+		return
+	}
+
+	i, ok := n.childIndex(child.compact.label[0])
+	if ok {
+		n.compact.children[i] = child
+		return
+	}
+
+	children := append(n.compact.children, nil)
+	copy(children[i+1:], children[i:])
+	children[i] = child
+	n.compact.children = children
+}
+
+/*
+COMPACT INSERT FLOW
+1. Find the child edge starting with the next rune of the word
+2. Add the rest of the word as a new edge if there isn't one
+3. Otherwise split the edge where it stops matching the word, and carry on from the end of the matching part
+*/
+
+// Walks word down from a compact node, adding and splitting edges as needed, and returns the node at the end of the word
+func (n *FuzzyMatcherNode) InsertCompact(word []rune) *FuzzyMatcherNode {
+	node := n
+	for len(word) > 0 {
+		// 1.
+		i, ok := node.childIndex(word[0])
+
+		// 2.
+		if !ok {
+			leaf := NewNode(CompactLayout, word, node)
+			node.AddChild(leaf)
+			return leaf
+		}
+
+		// 3.
+		child := node.compact.children[i]
+		label := child.compact.label
+
+		common := 1
+		for common < len(label) && common < len(word) && label[common] == word[common] {
+			common++
+		}
+
+		if common < len(label) {
+			child = child.split(common)
+		}
+
+		node = child
+		word = word[common:]
+	}
+
+	return node
+}
+
+// Merges a compact node into its only child when it holds no IDs, keeping edges compressed
+// The node is detached from the trie once merged
+func (n *FuzzyMatcherNode) Compress() {
+	c := n.compact
+	if c == nil || c.edge != nil || n.Parent == nil || n.IsEndofString || len(c.ids) > 0 || len(c.children) != 1 {
+		return
+	}
+
+	child := c.children[0]
+	label := make([]rune, 0, len(c.label)+len(child.compact.label))
+	label = append(label, c.label...)
+	child.compact.label = append(label, child.compact.label...)
+	child.Parent = n.Parent
+
+	n.Parent.AddChild(child)
+	n.Parent = nil
+	c.children = nil
+}
+
+// Splits the edge leading to a compact node after offset runes, returning the node added at the split
+func (n *FuzzyMatcherNode) split(offset int) *FuzzyMatcherNode {
+	label := n.compact.label
+
+	mid := NewNode(CompactLayout, label[:offset:offset], n.Parent)
+	mid.Count = n.Count
+	mid.compact.children = []*FuzzyMatcherNode{n}
+
+	n.Parent.AddChild(mid)
+	n.Parent = mid
+	n.compact.label = label[offset:]
+
+	return mid
+}
+
+// Returns the position at offset along the edge leading to a compact node, parent is the position before it
+func (n *FuzzyMatcherNode) position(offset int, parent *FuzzyMatcherNode) *FuzzyMatcherNode {
+	label := n.compact.label
+	if offset == len(label)-1 {
+		return n
+	}
+
+	// Positions inside an edge have no IDs and lead only to the edge's node, so they share its count
+	return &FuzzyMatcherNode{
+		Char:    label[offset],
+		Parent:  parent,
+		Count:   n.Count,
+		compact: &compactNode{label: label[:offset+1], edge: n},
+	}
+}
+
+// Returns the index of the child whose label starts with c, or where it would be inserted
+func (n *FuzzyMatcherNode) childIndex(c rune) (int, bool) {
+	children := n.compact.children
+	i := sort.Search(len(children), func(i int) bool {
+		return children[i].compact.label[0] >= c
+	})
+
+	return i, i < len(children) && children[i].compact.label[0] == c
+}
+
+// Returns a rough estimate of the memory used by the node, its children and IDs, not counting the child nodes themselves
+func (n *FuzzyMatcherNode) EstimatedBytes() int64 {
+	size := int64(unsafe.Sizeof(*n))
+
+	if c := n.compact; c != nil {
+		size += int64(unsafe.Sizeof(*c))
+		size += int64(cap(c.children)) * int64(unsafe.Sizeof(n))
+		size += int64(cap(c.ids)) * int64(unsafe.Sizeof(int(0)))
+		size += int64(cap(c.label)) * int64(unsafe.Sizeof(rune(0)))
+		return size
+	}
+
+	if n.Children != nil {
+		size += EstimateMapBytes(len(n.Children), int64(unsafe.Sizeof(rune(0))+unsafe.Sizeof(n)))
+	}
+	if n.ID != nil {
+		size += EstimateMapBytes(len(n.ID), int64(unsafe.Sizeof(int(0))+unsafe.Sizeof(true)))
+	}
+
+	return size
+}
+
+// Estimates the size of a map from its number of entries and the size of a key and value, assuming groups of 8 slots with a control word that are kept at most 7/8 full
+func EstimateMapBytes(entries int, slotBytes int64) int64 {
+	const header = 48
+
+	groups := int64(1)
+	for groups*7 < int64(entries) {
+		groups *= 2
+	}
+
+	return header + groups*(8+8*slotBytes)
+}
@@ -1,5 +1,10 @@
 package fuzzymatchertypes
 
+import (
+    "context"
+    "sync/atomic"
+)
+
 // RecurseParameters contains all parameters needed for recursive matching
 type RecurseParameters struct {
     Word              []rune
@@ -10,17 +15,71 @@ type RecurseParameters struct {
     MaxDepth          int
     Depth             int
     DepthIncrement    int
-    NumEdits          int
+    NumEdits          float64 // Cost of the edits taken so far
     MaxEdits          int
-    NumEditsIncrement int
+    NumEditsIncrement float64
     EditableFields    []bool
     Visited           map[VisitKey]struct{}
     CalculationMethod CalculationMethod
     MinDistance       float64
+    Explain           bool // Records the edits taken on each branch
+    Edits             []Edit
+    Budget            *SearchBudget // Shared by every branch of a search, nil for no limit
+    Explored          map[ExploredState]ExploredCost // Cheapest way each state was reached, shared by every branch of a field's search
+    MaxMatches        int // Values found before the A* engine stops, 0 for no limit
+}
+
+// ExploredState is a trie position paired with an index in the search word
+type ExploredState struct {
+    Position NodePosition
+    Index    int
+}
+
+// ExploredCost is the edit cost and depth a state was reached with
+type ExploredCost struct {
+    Edits float64
+    Depth int
+}
+
+// Returns false if the branch's state was already reached as cheaply, as nothing new can be found from it
+// Records the state otherwise, branches without an Explored map are always explored
+func (rp *RecurseParameters) Explore() bool {
+    edits := rp.NumEdits + rp.NumEditsIncrement
+    depth := rp.Depth + rp.DepthIncrement
+    if !rp.Unexplored(rp.Node, rp.Index, edits, depth) {
+        return false
+    }
+
+    if rp.Explored != nil {
+        rp.Explored[ExploredState{Position: rp.Node.Position(), Index: rp.Index}] = ExploredCost{Edits: edits, Depth: depth}
+    }
+    return true
+}
+
+// Returns true if a branch reaching node at index with the given edits and depth would find anything new, without recording it
+// Lets callers skip cloning branches that Explore would turn away
+func (rp *RecurseParameters) Unexplored(node *FuzzyMatcherNode, index int, edits float64, depth int) bool {
+    if rp.Explored == nil {
+        return true
+    }
+
+    explored, ok := rp.Explored[ExploredState{Position: node.Position(), Index: index}]
+    return !ok || explored.Edits > edits || explored.Depth > depth
+}
+
+// Returns true if there's room for at most one more edit, cheapest is the least any edit costs
+// With fractional costs several edits can still fit in the last unit of the budget
+func (rp *RecurseParameters) LastEdit(cheapest float64) bool {
+    remaining := float64(rp.MaxEdits) - rp.NumEdits
+    return remaining > 0 && remaining < 2*cheapest-editCostTolerance
 }
 
 func (rp *RecurseParameters) Clone() RecurseParameters {
     newPath := append([]rune{}, rp.Path...)
+    var newEdits []Edit
+    if rp.Explain {
+        newEdits = append(newEdits, rp.Edits...)
+    }
     newVisited := make(map[VisitKey]struct{}, len(rp.Visited))
     for k, v := range rp.Visited {
         newVisited[k] = v
@@ -42,5 +101,62 @@ func (rp *RecurseParameters) Clone() RecurseParameters {
         Visited:           newVisited,
         CalculationMethod: rp.CalculationMethod,
 		MinDistance:       rp.MinDistance,
+        Explain:           rp.Explain,
+        Edits:             newEdits,
+        Budget:            rp.Budget,
+        Explored:          rp.Explored,
+        MaxMatches:        rp.MaxMatches,
+    }
+}
+
+// SearchBudget stops a search once its context is done or it has visited too many nodes
+// A single budget is shared by the branches and field goroutines of a search, so it is safe for concurrent use
+type SearchBudget struct {
+    Ctx      context.Context
+    MaxNodes int64 // Maximum nodes visited, 0 for no limit
+    nodes    atomic.Int64
+    stopped  atomic.Bool
+}
+
+// Counts a visited node, returns false once the search should stop
+// A nil budget never stops
+func (b *SearchBudget) Visit() bool {
+    if b == nil {
+        return true
+    }
+
+    if b.stopped.Load() {
+        return false
+    }
+
+    nodes := b.nodes.Add(1)
+    if b.MaxNodes > 0 && nodes > b.MaxNodes {
+        b.stopped.Store(true)
+        return false
+    }
+
+    if b.Ctx != nil {
+        select {
+        case <-b.Ctx.Done():
+            b.stopped.Store(true)
+            return false
+        default:
+        }
     }
-}
\ No newline at end of file
+
+    return true
+}
+
+// Returns true if the search was stopped before it finished
+func (b *SearchBudget) Stopped() bool {
+    return b != nil && b.stopped.Load()
+}
+
+// Returns the number of nodes visited so far
+func (b *SearchBudget) Nodes() int64 {
+    if b == nil {
+        return 0
+    }
+
+    return b.nodes.Load()
+}
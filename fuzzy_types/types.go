@@ -1,6 +1,8 @@
 package fuzzymatchertypes
 
 import (
+	"encoding/json"
+	"sort"
 	"time"
 )
 
@@ -9,11 +11,31 @@ type CalculationMethod string
 
 // Calculation methods
 const (
-    JaroWinkler CalculationMethod = "jaro"
-    Levenshtein CalculationMethod = "levenshtein"
-    Default     CalculationMethod = ""
+    JaroWinkler        CalculationMethod = "jaro"
+    Levenshtein        CalculationMethod = "levenshtein"
+    DamerauLevenshtein CalculationMethod = "damerau" // Levenshtein counting a swap of adjacent characters as one edit
+    Default            CalculationMethod = ""
 )
 
+// Phonetic calculation methods, these compare sound codes rather than spelling
+const (
+    Soundex   CalculationMethod = "soundex"
+    Metaphone CalculationMethod = "metaphone"
+    NYSIIS    CalculationMethod = "nysiis"
+)
+
+// Metric scores how similar two strings are, between 0 and 1 where 1 is a 100% match
+type Metric interface {
+    Similarity(s1, s2 string) float64
+}
+
+// SimilarityFunc allows a plain function to be used as a Metric
+type SimilarityFunc func(s1, s2 string) float64
+
+func (f SimilarityFunc) Similarity(s1, s2 string) float64 {
+    return f(s1, s2)
+}
+
 // Common field types
 const (
     Firstname  Field = "firstname"
@@ -31,6 +53,7 @@ type FuzzyEntry struct {
 }
 
 // FuzzyMatcherNode represents a node in the FuzzyMatcher trie structure
+// Children and ID are only used by the map layout, use the node's methods to work with either layout
 type FuzzyMatcherNode struct {
     Char          rune
     Children      map[rune]*FuzzyMatcherNode
@@ -38,28 +61,386 @@ type FuzzyMatcherNode struct {
     ID            map[int]bool
     Parent        *FuzzyMatcherNode
     Count         int
+    compact       *compactNode
 }
 
 // FuzzyMatch represents a match result with score
 type FuzzyMatch[T FuzzyMatcherDataSource] struct {
-    Entry T
-    Score float64
+    Entry    T
+    Score    float64
+    Synonyms map[Field]string // Fields that matched through a synonym, mapped to the synonym used
+    Explanation map[Field]FieldExplanation // How each field was matched and scored, only set when SearchOptions.Explain is true
+}
+
+// EditOperation is a kind of edit applied to the query value while searching the trie
+type EditOperation string
+
+// Edit operations
+const (
+    EditSubstitution EditOperation = "substitution" // A query character replaced with a different one
+    EditSkip         EditOperation = "skip"         // A query character skipped
+    EditExtension    EditOperation = "extension"    // A character added past the end of the query value, these are free
+    EditOcrSingle    EditOperation = "ocr_single"   // A single character OCR misread corrected, e.g. '1' for 'i'
+    EditOcrMulti     EditOperation = "ocr_multi"    // A multi character OCR misread corrected, e.g. "rn" for 'm'
+    EditTransposition EditOperation = "transposition" // Two adjacent query characters swapped, e.g. "jhon" for "john"
+    EditInsertion    EditOperation = "insertion"    // A character added in the middle of the query value, e.g. "jon" for "john"
+)
+
+// Edit is a single edit applied to the query value
+type Edit struct {
+    Operation EditOperation
+    Position  int    // Position in the query value, positions past the end are extensions
+    From      string // Query characters replaced or skipped
+    To        string // Characters taken from the trie
+}
+
+// EditCosts sets what edits cost against MaxEdits, edits without a cost cost 1 and extensions are always free
+// Costs can be fractional but shouldn't be negative
+type EditCosts struct {
+    Operations map[EditOperation]float64 // Cost of each kind of edit, e.g. {EditOcrSingle: 0.5}
+    Pairs      map[EditPair]float64      // Cost of specific replacements, used over the operation's cost, e.g. {{"0", "o"}: 0.25}
+}
+
+// EditPair is a replacement of query characters with trie characters
+// From is empty for insertions and To is empty for skips
+type EditPair struct {
+    From string
+    To   string
+}
+
+// Returns the cost of an edit
+func (ec EditCosts) Cost(operation EditOperation, from, to string) float64 {
+    if cost, ok := ec.Pairs[EditPair{From: from, To: to}]; ok {
+        return cost
+    }
+
+    if cost, ok := ec.Operations[operation]; ok {
+        return cost
+    }
+
+    return 1
+}
+
+// Returns the least any edit can cost, used as a lower bound on the edits left to a match
+func (ec EditCosts) Cheapest() float64 {
+    cheapest := 1.0
+    for _, operation := range []EditOperation{EditSubstitution, EditSkip, EditOcrSingle, EditOcrMulti, EditTransposition, EditInsertion} {
+        if cost, ok := ec.Operations[operation]; ok {
+            cheapest = min(cheapest, cost)
+        }
+    }
+
+    for _, cost := range ec.Pairs {
+        cheapest = min(cheapest, cost)
+    }
+
+    return max(cheapest, 0)
+}
+
+// Tolerance for rounding when fractional costs are added up
+const editCostTolerance = 1e-9
+
+// Returns true if the edit cost is over the maxEdits budget
+func ExceedsEdits(cost float64, maxEdits int) bool {
+    return cost > float64(maxEdits)+editCostTolerance
+}
+
+// FieldExplanation describes how a single field of a match was found and scored
+type FieldExplanation struct {
+    Query        string            // Normalized query value
+    Searched     string            // Value the trie was searched for, the query value or one of its synonyms
+    Matched      string            // Normalized matched value, empty when the field didn't match
+    Synonym      string            // Synonym the value was scored against, if any
+    Phonetic     CalculationMethod // Sound code index the value was found through, if any
+    Edits        []Edit            // Edits applied to the searched value on the cheapest path to the matched value
+    Method       CalculationMethod // Calculation method used for the similarity
+    Similarity   float64           // Field similarity, 0 when it was below the field's min distance
+    Weight       float64           // Field weight
+    Contribution float64           // Weight * similarity, the contributions add up to the match score
 }
 
 // FuzzyMatcherParameters defines the search parameters for fuzzy matching
 type FuzzyMatcherParameters struct {
     MaxDepth           map[Field]int               // Maximum search depth for each field
-    MaxEdits           map[Field]int               // Maximum number of edits allowed for each field
+    MaxEdits           map[Field]int               // Maximum number of edits allowed for each field, or their total cost when EditCosts are set
     Weights            map[Field]float64           // Weights for each field
     CalculationMethods map[Field]CalculationMethod // Calculation method for each field
     MinDistances       map[Field]float64           // Minimum distance for each field
 }
 
+// TieBreakPolicy defines how matches with equal scores are ordered
+type TieBreakPolicy string
+
+// Tie-break policies
+const (
+    TieBreakID     TieBreakPolicy = ""         // Lowest ID first
+    TieBreakIDDesc TieBreakPolicy = "id_desc"  // Highest ID first
+    TieBreakEdits  TieBreakPolicy = "edits"    // Cheapest total edits first, then lowest ID
+)
+
+// SearchOptions defines per-call result limits, score floor and paging
+type SearchOptions struct {
+    Limit    int            // Maximum number of matches returned, 0 uses the default limit, < 0 returns every match
+    Offset   int            // Number of ranked matches to skip before the limit is applied
+    MinScore float64        // Minimum aggregate score a match needs to be returned
+    TieBreak TieBreakPolicy // Ordering for matches with equal scores
+    Explain  bool           // Attaches an explanation of each field to the matches
+    MaxNodes int            // Maximum trie nodes a search may visit across all fields, 0 for no limit
+    MaxFieldMatches int     // Cheapest values kept for each field with the A* engine, or values sharing the most n-grams with the n-gram backend, 0 for no limit
+}
+
+// BatchOptions defines how a batch of searches is run
+type BatchOptions struct {
+    SearchOptions     // Options used for every search in the batch
+    Workers       int // Number of searches run at once, defaults to GOMAXPROCS
+}
+
+// BatchResult is the result of a single search in a batch
+type BatchResult[T FuzzyMatcherDataSource] struct {
+    Found   bool
+    Matches []FuzzyMatch[T]
+    Err     error // Set when the search was cut short, Matches then holds the partial results
+}
+
+// SearchEngine is how a field's value is matched against the trie
+type SearchEngine string
+
+// Search engines
+const (
+    RecursiveEngine SearchEngine = ""          // Recurse with a breadth first search for the rest of the value, characters past the end of the query are free
+    AutomatonEngine SearchEngine = "automaton" // Levenshtein automaton, every value within MaxEdits is found exactly once at its cheapest
+    AStarEngine     SearchEngine = "astar"     // A* search, values are found cheapest first so MaxFieldMatches can stop it early, characters past the end of the query are free
+)
+
+// FieldBackend indexes a field's values for fuzzy lookup alongside the trie, and is searched instead of it
+// The core keeps a backend up to date as entries are built, upserted, removed and expire
+type FieldBackend interface {
+    Insert(value string, id int)                      // Adds an ID to a normalized value
+    Remove(value string, id int)                      // Removes an ID from a normalized value, values left without IDs are dropped
+    Search(params RecurseParameters) []MatchCandidate // Finds the values within params.MaxEdits of the value in params.Word, Text is prefixed with the field like the trie's
+}
+
+// BackendKind is the index a field's values are searched with
+type BackendKind string
+
+// Field backends
+const (
+    TrieBackend     BackendKind = ""         // The trie, searched with the core's SearchEngine
+    SymSpellBackend BackendKind = "symspell" // Symmetric delete index, fast for short values with small edit budgets like postal codes
+    BKTreeBackend   BackendKind = "bktree"   // Burkhard-Keller tree, searched with the field's calculation method and min distance rather than edits
+    NGramBackend    BackendKind = "ngram"    // Inverted index of n-grams, finds values sharing enough n-grams with the query for long fields like addresses
+)
+
 // FuzzyMatcherCoreParameters defines core behavior of the fuzzy matcher
 type FuzzyMatcherCoreParameters[T FuzzyMatcherDataSource] struct {
     CorrectOcrMisreads bool
     MaxEdits           int
     UseExpiration      bool
+    PhoneticIndexes    map[Field]CalculationMethod // Fields also indexed by sound code, e.g. {"surname": Soundex}
+    Synonyms           map[Field]SynonymDictionary // Nicknames and synonyms searched for each field
+    SynonymScore       float64                     // Similarity given to a synonym match, defaults to 0.9
+    Normalization      map[Field]NormalizationPipeline // Normalization for each field, fields without one keep only a-z and 0-9
+    NodeLayout         NodeLayout                      // How trie nodes are stored, CompactLayout uses far less memory
+    EditCosts          EditCosts                       // What each edit costs, MaxEdits is then a budget rather than a count
+    OcrConfusions      OcrConfusions                   // Misreads corrected along with the built in ones, read when the core is built or loaded
+    ReplaceOcrConfusions bool                          // Only corrects OcrConfusions, leaving out the built in misreads
+    OcrProbabilities   OcrProbabilities                // Trained misreads, also corrected, likely misreads are tried first
+    MinOcrProbability  float64                         // Misreads with a lower probability aren't corrected, misreads without one always are
+    SearchEngine       SearchEngine                    // How field values are matched, defaults to RecursiveEngine
+    FieldBackends      map[Field]BackendKind           // Fields searched with another index instead of the trie, e.g. {"postcode": SymSpellBackend}
+    SymSpellMaxEdits   int                             // Edit budget the SymSpell index is built for, defaults to 2, with EditCosts it holds as many edits as fit at the cheapest cost
+    NGramSize          int                             // Characters in each n-gram of the n-gram index, defaults to 3
+    NGramMinOverlap    float64                         // Share of the query's n-grams a value needs to be a candidate, defaults to 0.5
+}
+
+// UnicodeForm is a unicode normalization form
+type UnicodeForm string
+
+// Unicode normalization forms
+const (
+    NoUnicodeForm UnicodeForm = ""
+    NFC           UnicodeForm = "NFC"
+    NFD           UnicodeForm = "NFD"
+    NFKC          UnicodeForm = "NFKC"
+    NFKD          UnicodeForm = "NFKD"
+)
+
+// CharacterPolicy defines what happens to a class of characters during normalization
+type CharacterPolicy string
+
+// Character policies
+const (
+    StripCharacters   CharacterPolicy = ""      // Removes the characters
+    KeepCharacters    CharacterPolicy = "keep"  // Leaves the characters as they are
+    SpaceCharacters   CharacterPolicy = "space" // Replaces runs of the characters with a single space, trimmed at either end
+)
+
+// NormalizationPipeline defines how a field value is normalized before it is inserted, searched or removed
+// Steps run in field order, letters and digits from any script are always kept
+type NormalizationPipeline struct {
+    Transliterations map[rune]string // Replaces runes before anything else, e.g. {'ß': "ss", 'ø': "o"}
+    Unicode          UnicodeForm     // Unicode normalization form, e.g. NFKD
+    FoldDiacritics   bool            // Removes combining marks, e.g. "José" becomes "Jose"
+    FoldCase         bool            // Unicode case folding, e.g. "MÜLLER" becomes "müller"
+    Punctuation      CharacterPolicy // Policy for punctuation and symbols
+    Whitespace       CharacterPolicy // Policy for whitespace
+}
+
+// SynonymDictionary maps a value to its canonical variants, e.g. {"ben": ["benjamin", "benedict"]}
+type SynonymDictionary map[string][]string
+
+// Returns a copy of the dictionary where each variant also maps back to the value, e.g. {"benjamin": ["ben"]}
+func (d SynonymDictionary) WithReverse() SynonymDictionary {
+    reversed := make(SynonymDictionary, len(d))
+    add := func(from, to string) {
+        for _, existing := range reversed[from] {
+            if existing == to {
+                return
+            }
+        }
+        reversed[from] = append(reversed[from], to)
+    }
+
+    for value, variants := range d {
+        for _, variant := range variants {
+            add(value, variant)
+            add(variant, value)
+        }
+    }
+
+    return reversed
+}
+
+// OcrConfusions maps characters an OCR engine read to the characters they might have been, e.g. {"rn": ["m"], "0": ["o", "d"]}
+// Either side can be any number of characters
+type OcrConfusions map[string][]string
+
+// Returns a copy of the confusions with other's confusions added
+func (c OcrConfusions) Merge(other OcrConfusions) OcrConfusions {
+    merged := make(OcrConfusions, len(c)+len(other))
+    add := func(from, to string) {
+        for _, existing := range merged[from] {
+            if existing == to {
+                return
+            }
+        }
+        merged[from] = append(merged[from], to)
+    }
+
+    for _, confusions := range []OcrConfusions{c, other} {
+        for from, tos := range confusions {
+            for _, to := range tos {
+                add(from, to)
+            }
+        }
+    }
+
+    return merged
+}
+
+// OcrProbabilities is how likely each misread is, e.g. {{From: "rn", To: "m"}: 0.8} when "rn" was really "m" 80% of the times it was read
+type OcrProbabilities map[EditPair]float64
+
+// Returns the misreads as confusions, the most likely first
+func (p OcrProbabilities) Confusions() OcrConfusions {
+    pairs := make([]EditPair, 0, len(p))
+    for pair := range p {
+        pairs = append(pairs, pair)
+    }
+    sort.Slice(pairs, func(i, j int) bool {
+        if p[pairs[i]] != p[pairs[j]] {
+            return p[pairs[i]] > p[pairs[j]]
+        }
+        return pairs[i].To < pairs[j].To
+    })
+
+    confusions := make(OcrConfusions)
+    for _, pair := range pairs {
+        confusions[pair.From] = append(confusions[pair.From], pair.To)
+    }
+
+    return confusions
+}
+
+// Encodes the probabilities as {"rn": {"m": 0.8}}
+func (p OcrProbabilities) MarshalJSON() ([]byte, error) {
+    nested := make(map[string]map[string]float64)
+    for pair, probability := range p {
+        if nested[pair.From] == nil {
+            nested[pair.From] = make(map[string]float64)
+        }
+        nested[pair.From][pair.To] = probability
+    }
+
+    return json.Marshal(nested)
+}
+
+// Decodes probabilities written by MarshalJSON
+func (p *OcrProbabilities) UnmarshalJSON(data []byte) error {
+    nested := make(map[string]map[string]float64)
+    if err := json.Unmarshal(data, &nested); err != nil {
+        return err
+    }
+
+    *p = make(OcrProbabilities)
+    for from, tos := range nested {
+        for to, probability := range tos {
+            (*p)[EditPair{From: from, To: to}] = probability
+        }
+    }
+
+    return nil
+}
+
+// OcrTrainingPair is a value as the OCR engine read it and as it was corrected
+type OcrTrainingPair struct {
+    Read      string `json:"read"`
+    Corrected string `json:"corrected"`
+}
+
+// OcrTrainingOptions controls which misreads are learnt from training pairs
+type OcrTrainingOptions struct {
+    MaxLength int // Longest misread learnt on either side, defaults to 3, longer differences are treated as noise
+    MinCount  int // Times a misread has to be seen to be learnt, defaults to 1
+}
+
+// AuditReport lists the inconsistencies found in a fuzzy matcher's trie
+type AuditReport struct {
+    Nodes                 int  // Nodes walked, including the root
+    CountMismatches       int  // Nodes whose Count isn't the number of IDs at or below them
+    OrphanedIDs           int  // IDs held by a node without an entry in Entries
+    EndOfStringWithoutIDs int  // Nodes flagged as end of string that hold no IDs
+    IDsWithoutEndOfString int  // Nodes holding IDs that aren't flagged as end of string
+    DanglingNodes         int  // Leaf nodes holding no IDs that should have been pruned
+    EntriesWithoutNodes   int  // Entries whose ID isn't held by any node
+    IndexMismatches       int  // IDs whose nodes in the reverse index don't match the trie
+    Repaired              bool // Set when the inconsistencies were repaired
+}
+
+// Returns true if no inconsistencies were found
+func (r AuditReport) Consistent() bool {
+    return r.CountMismatches == 0 &&
+        r.OrphanedIDs == 0 &&
+        r.EndOfStringWithoutIDs == 0 &&
+        r.IDsWithoutEndOfString == 0 &&
+        r.DanglingNodes == 0 &&
+        r.EntriesWithoutNodes == 0 &&
+        r.IndexMismatches == 0
+}
+
+// IndexStats describes the size and shape of a fuzzy matcher's trie
+type IndexStats struct {
+    Nodes              int            // Nodes in the trie, including the root
+    KeyNodes           int            // Nodes spelling out field keys, shared by every value of the field
+    NodesByKey         map[string]int // Nodes below each key, by the key before the ':', e.g. "surname" and "surname~metaphone"
+    EndOfStringNodes   int            // Nodes holding at least one ID
+    IDs                int            // IDs held across all end of string nodes
+    Entries            int            // Entries stored in the matcher
+    AverageDepth       float64        // Average length in runes of the indexed values, not counting the key
+    MaxDepth           int            // Longest indexed value in runes, not counting the key
+    BranchingHistogram map[int]int    // Number of nodes by how many children they have
+    PendingExpiries    int            // Expiries waiting in the heap, including ones already removed or upserted
+    EstimatedBytes     int64          // Rough size of the trie, reverse index and expiry heap, entries aren't included
 }
 
 // VisitKey is a key to identify visited nodes during recursion
@@ -68,9 +449,12 @@ type VisitKey uint64
 // MatchCandidate represents a potential match during search
 type MatchCandidate struct {
     Text        string
-    EditCount   int
+    EditCount   float64 // Total cost of the edits on the path, the number of edits unless EditCosts are set
     SearchDepth int
     ID          []int
+    Synonym     string            // Set when the candidate was found by searching for a synonym of the query value
+    Phonetic    CalculationMethod // Set when the candidate was found through a sound code index
+    Edits       []Edit            // Edits on the path to the candidate, only recorded when explaining
 }
 
 type ExpiryEntry struct {
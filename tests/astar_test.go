@@ -0,0 +1,168 @@
+package fuzzymatchertests
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	fc "github.com/oiamo123/fuzzy_matcher/fuzzy_classes"
+	fmc "github.com/oiamo123/fuzzy_matcher/fuzzy_matcher_core"
+	ft "github.com/oiamo123/fuzzy_matcher/fuzzy_types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFuzzyMatcherCore_AStarEngine(t *testing.T) {
+	members := loadWaveMembersTestData(t)
+
+	newCore := func(t *testing.T, params ft.FuzzyMatcherCoreParameters[fc.ExampleSource]) *fmc.FuzzyMatcherCore[fc.ExampleSource] {
+		params.SearchEngine = ft.AStarEngine
+		fuzzyMatcherCore := &fmc.FuzzyMatcherCore[fc.ExampleSource]{CoreParams: params}
+		require.NoError(t, fuzzyMatcherCore.Build(members))
+		return fuzzyMatcherCore
+	}
+
+	fuzzyMatcherCore := newCore(t, ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{MaxEdits: 6})
+
+	values := map[string]bool{}
+	for _, member := range members {
+		values[fuzzyMatcherCore.NormalizeFieldFor(ft.Firstname, member.Firstname)] = true
+	}
+
+	search := func(query string, maxEdits, maxMatches int) []ft.MatchCandidate {
+		params := fuzzyMatcherCore.NewRecurseParameters(ft.Firstname, fuzzyMatcherCore.NormalizeFieldFor(ft.Firstname, query), ft.FuzzyMatcherParameters{
+			MaxEdits: map[ft.Field]int{ft.Firstname: maxEdits},
+		})
+		params.MaxMatches = maxMatches
+		return fuzzyMatcherCore.SearchAStar(params)
+	}
+
+	// Characters past the end of the query are free, so a value costs as little as its cheapest prefix
+	cheapestPrefix := func(query, value string) int {
+		cheapest := optimalStringAlignment(query, "")
+		for i := range []rune(value) {
+			cheapest = min(cheapest, optimalStringAlignment(query, string([]rune(value)[:i+1])))
+		}
+		return cheapest
+	}
+
+	queries := []string{"Jon", "Micheal", "Sarha", "Emly", "Davd", "Chris", "Ana"}
+
+	t.Run("FindsEveryValueWithinMaxEdits", func(t *testing.T) {
+		for _, query := range queries {
+			for maxEdits := 0; maxEdits <= 2; maxEdits++ {
+				normalized := fuzzyMatcherCore.NormalizeFieldFor(ft.Firstname, query)
+
+				expected := map[string]float64{}
+				for value := range values {
+					if distance := cheapestPrefix(normalized, value); distance <= maxEdits {
+						expected[value] = float64(distance)
+					}
+				}
+
+				found := map[string]float64{}
+				for _, match := range search(query, maxEdits, 0) {
+					value := strings.TrimPrefix(match.Text, string(ft.Firstname)+":")
+					_, duplicate := found[value]
+					assert.False(t, duplicate, "%s found %s twice within %d edits", query, value, maxEdits)
+					found[value] = match.EditCount
+				}
+
+				assert.Equal(t, expected, found, "%s within %d edits", query, maxEdits)
+			}
+		}
+	})
+
+	t.Run("CheapestFirst", func(t *testing.T) {
+		for _, query := range queries {
+			matches := search(query, 2, 0)
+			assert.True(t, sort.SliceIsSorted(matches, func(i, j int) bool {
+				return matches[i].EditCount < matches[j].EditCount
+			}), query)
+		}
+	})
+
+	t.Run("MaxMatches", func(t *testing.T) {
+		for _, query := range queries {
+			all := search(query, 2, 0)
+			if len(all) < 3 {
+				continue
+			}
+
+			top := search(query, 2, 3)
+			require.Len(t, top, 3, query)
+			for i := range top {
+				assert.Equal(t, all[i].EditCount, top[i].EditCount, query)
+			}
+		}
+	})
+
+	t.Run("Explain", func(t *testing.T) {
+		explainCore := newCore(t, ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{MaxEdits: 6, CorrectOcrMisreads: true})
+
+		tests := []struct {
+			name      string
+			firstname string
+			edits     []ft.Edit
+		}{
+			{"Insertion", "Mchael", []ft.Edit{{Operation: ft.EditInsertion, Position: 1, To: "i"}}},
+			{"Transposition", "Micheal", []ft.Edit{{Operation: ft.EditTransposition, Position: 4, From: "ea", To: "ae"}}},
+			{"OcrMultiChar", "Rnichael", []ft.Edit{{Operation: ft.EditOcrMulti, Position: 0, From: "rn", To: "m"}}},
+			{"Extensions", "Michae", []ft.Edit{{Operation: ft.EditExtension, Position: 6, To: "l"}}},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				found, matches := explainCore.SearchFuzzy(fc.ExampleSource{
+					ID:        999,
+					Firstname: tt.firstname,
+					Surname:   "Brown",
+					Birthdate: time.Date(1992, 8, 22, 0, 0, 0, 0, time.UTC),
+				}, ft.SearchOptions{Explain: true})
+				require.True(t, found)
+
+				firstname := matches[0].Explanation[ft.Firstname]
+				assert.Equal(t, "michael", firstname.Matched)
+				assert.Equal(t, tt.edits, firstname.Edits)
+			})
+		}
+	})
+
+	t.Run("SearchFuzzy", func(t *testing.T) {
+		searchCore := newCore(t, ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{MaxEdits: 6, CorrectOcrMisreads: true})
+
+		tests := []struct {
+			name       string
+			firstname  string
+			surname    string
+			birthdate  string
+			expectedID int
+		}{
+			{"Exact", "Sarah", "Johnson", "1985-12-03", 2},
+			{"MissingLetter", "Sarah", "Jonson", "1985-12-03", 2},
+			{"WrongLetter", "Olivia", "Milker", "1993-07-25", 6},
+			{"Transposition", "Sarah", "Jonhson", "1985-12-03", 2},
+			{"OcrMisread", "Ernma", "Davis", "1988-03-10", 4},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				birthdate, err := time.Parse("2006-01-02", tt.birthdate)
+				require.NoError(t, err)
+
+				query := fc.ExampleSource{ID: 999, Firstname: tt.firstname, Surname: tt.surname, Birthdate: birthdate}
+
+				found, matches := searchCore.SearchFuzzy(query)
+				require.True(t, found)
+				assert.Equal(t, tt.expectedID, matches[0].Entry.ID)
+
+				// Only the cheapest value of each field is kept, the exact values are still found
+				found, matches = searchCore.SearchFuzzy(query, ft.SearchOptions{MaxFieldMatches: 1})
+				require.True(t, found)
+				assert.Equal(t, tt.expectedID, matches[0].Entry.ID)
+			})
+		}
+	})
+}
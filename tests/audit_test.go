@@ -0,0 +1,129 @@
+package fuzzymatchertests
+
+import (
+	"testing"
+	"time"
+
+	fc "github.com/oiamo123/fuzzy_matcher/fuzzy_classes"
+	fmc "github.com/oiamo123/fuzzy_matcher/fuzzy_matcher_core"
+	ft "github.com/oiamo123/fuzzy_matcher/fuzzy_types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFuzzyMatcherCore_CountBookkeeping(t *testing.T) {
+	members := loadTestData(t)
+
+	newCore := func(t *testing.T, members []fc.ExampleSource) *fmc.FuzzyMatcherCore[fc.ExampleSource] {
+		core := &fmc.FuzzyMatcherCore[fc.ExampleSource]{CoreParams: ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{MaxEdits: 6}}
+		require.NoError(t, core.Build(members))
+		return core
+	}
+
+	t.Run("RemoveEntries", func(t *testing.T) {
+		core := newCore(t, members)
+		core.RemoveEntries(members[:5])
+
+		assert.True(t, core.Audit(false).Consistent())
+		assert.Equal(t, trieShape(newCore(t, members[5:]).Root), trieShape(core.Root))
+	})
+
+	t.Run("BuildTwice", func(t *testing.T) {
+		core := newCore(t, members)
+		require.NoError(t, core.Build(members))
+
+		assert.True(t, core.Audit(false).Consistent())
+		assert.Equal(t, trieShape(newCore(t, members).Root), trieShape(core.Root))
+	})
+
+	t.Run("Clean", func(t *testing.T) {
+		expiring := []fc.ExampleSource{
+			{ID: 1, Firstname: "John", Surname: "Smith", Birthdate: time.Date(1990, 5, 15, 0, 0, 0, 0, time.UTC), EventEndUtc: time.Now().Add(-24 * time.Hour)},
+			{ID: 2, Firstname: "Johnny", Surname: "Smith", Birthdate: time.Date(1990, 5, 15, 0, 0, 0, 0, time.UTC), EventEndUtc: time.Now().Add(24 * time.Hour)},
+		}
+
+		core := &fmc.FuzzyMatcherCore[fc.ExampleSource]{CoreParams: ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{MaxEdits: 6, UseExpiration: true}}
+		require.NoError(t, core.Build(expiring))
+		core.Clean()
+
+		assert.True(t, core.Audit(false).Consistent())
+		assert.NotContains(t, core.Entries, 1, "Expired entries should be dropped")
+		assert.Equal(t, trieShape(newCore(t, expiring[1:]).Root), trieShape(core.Root))
+	})
+}
+
+func TestFuzzyMatcherCore_Audit(t *testing.T) {
+	members := loadTestData(t)
+
+	core := &fmc.FuzzyMatcherCore[fc.ExampleSource]{CoreParams: ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{MaxEdits: 6}}
+	require.NoError(t, core.Build(members))
+
+	report := core.Audit(false)
+	require.True(t, report.Consistent())
+	assert.Equal(t, countNodes(core.Root), report.Nodes)
+
+	// Corrupt the trie the way the bookkeeping used to drift
+	firstname := core.Root.Children['f']
+	firstname.Count += 3
+
+	johnPath := "firstname:" + core.NormalizeField(members[0].Firstname)
+	john := core.Root
+	for _, char := range johnPath {
+		john = john.Children[char]
+	}
+	john.ID[-1] = true
+
+	dangling := &ft.FuzzyMatcherNode{Char: '~', Parent: firstname, Children: map[rune]*ft.FuzzyMatcherNode{}, IsEndofString: true}
+	firstname.Children['~'] = dangling
+
+	core.Entries[-2] = fc.ExampleSource{ID: -2, Firstname: "Nobody"}
+
+	report = core.Audit(false)
+	assert.False(t, report.Consistent())
+	assert.False(t, report.Repaired)
+	assert.Equal(t, len(johnPath), report.CountMismatches, "Every node on the orphaned ID's path is off, the corrupted firstname node included")
+	assert.Equal(t, 1, report.OrphanedIDs)
+	assert.Equal(t, 1, report.EndOfStringWithoutIDs)
+	assert.Equal(t, 1, report.DanglingNodes)
+	assert.Equal(t, 1, report.EntriesWithoutNodes)
+	assert.Equal(t, 1, report.IndexMismatches, "The orphaned ID was added behind the index's back")
+
+	report = core.Audit(true)
+	assert.True(t, report.Repaired)
+
+	assert.True(t, core.Audit(false).Consistent())
+	assert.NotContains(t, core.Entries, -2)
+
+	fresh := &fmc.FuzzyMatcherCore[fc.ExampleSource]{CoreParams: ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{MaxEdits: 6}}
+	require.NoError(t, fresh.Build(members))
+	assert.Equal(t, trieShape(fresh.Root), trieShape(core.Root))
+}
+
+func TestFuzzyMatcherCore_AuditFieldBackends(t *testing.T) {
+	members := loadTestData(t)
+
+	for _, kind := range []ft.BackendKind{ft.SymSpellBackend, ft.BKTreeBackend, ft.NGramBackend} {
+		t.Run(string(kind), func(t *testing.T) {
+			core := &fmc.FuzzyMatcherCore[fc.ExampleSource]{CoreParams: ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{
+				MaxEdits: 6,
+				FieldBackends: map[ft.Field]ft.BackendKind{
+					ft.Firstname: kind,
+					ft.Surname:   kind,
+					ft.Birthdate: kind,
+				},
+			}}
+			require.NoError(t, core.Build(members))
+
+			// Dropping the entry orphans its ID in the trie and the backends
+			delete(core.Entries, members[0].ID)
+			require.True(t, core.Audit(true).Repaired)
+			assert.True(t, core.Audit(false).Consistent())
+
+			_, matches := core.SearchFuzzy(members[0], ft.SearchOptions{Limit: -1})
+			for _, match := range matches {
+				assert.NotEqual(t, fc.ExampleSource{}, match.Entry, "Backends shouldn't return IDs the repair removed")
+			}
+		})
+	}
+}
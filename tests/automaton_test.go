@@ -0,0 +1,218 @@
+package fuzzymatchertests
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	fc "github.com/oiamo123/fuzzy_matcher/fuzzy_classes"
+	fmc "github.com/oiamo123/fuzzy_matcher/fuzzy_matcher_core"
+	ft "github.com/oiamo123/fuzzy_matcher/fuzzy_types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Optimal string alignment distance, the edits the automaton counts without OCR corrections
+func optimalStringAlignment(a, b string) int {
+	s1, s2 := []rune(a), []rune(b)
+	distances := make([][]int, len(s1)+1)
+	for i := range distances {
+		distances[i] = make([]int, len(s2)+1)
+		distances[i][0] = i
+	}
+	for j := range distances[0] {
+		distances[0][j] = j
+	}
+
+	for i := 1; i <= len(s1); i++ {
+		for j := 1; j <= len(s2); j++ {
+			cost := 1
+			if s1[i-1] == s2[j-1] {
+				cost = 0
+			}
+			distances[i][j] = min(distances[i-1][j]+1, distances[i][j-1]+1, distances[i-1][j-1]+cost)
+			if i > 1 && j > 1 && s1[i-1] == s2[j-2] && s1[i-2] == s2[j-1] {
+				distances[i][j] = min(distances[i][j], distances[i-2][j-2]+1)
+			}
+		}
+	}
+
+	return distances[len(s1)][len(s2)]
+}
+
+func TestFuzzyMatcherCore_AutomatonEngine(t *testing.T) {
+	members := loadWaveMembersTestData(t)
+
+	newCore := func(t *testing.T, params ft.FuzzyMatcherCoreParameters[fc.ExampleSource]) *fmc.FuzzyMatcherCore[fc.ExampleSource] {
+		params.SearchEngine = ft.AutomatonEngine
+		fuzzyMatcherCore := &fmc.FuzzyMatcherCore[fc.ExampleSource]{CoreParams: params}
+		require.NoError(t, fuzzyMatcherCore.Build(members))
+		return fuzzyMatcherCore
+	}
+
+	t.Run("FindsEveryValueWithinMaxEdits", func(t *testing.T) {
+		fuzzyMatcherCore := newCore(t, ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{MaxEdits: 6})
+
+		values := map[string]bool{}
+		for _, member := range members {
+			values[fuzzyMatcherCore.NormalizeFieldFor(ft.Firstname, member.Firstname)] = true
+		}
+
+		for _, query := range []string{"Jon", "Micheal", "Sarha", "Emly", "Davd", "Chris", "Ana"} {
+			for maxEdits := 0; maxEdits <= 3; maxEdits++ {
+				normalized := fuzzyMatcherCore.NormalizeFieldFor(ft.Firstname, query)
+				params := fuzzyMatcherCore.NewRecurseParameters(ft.Firstname, normalized, ft.FuzzyMatcherParameters{
+					MaxEdits: map[ft.Field]int{ft.Firstname: maxEdits},
+				})
+
+				expected := map[string]float64{}
+				for value := range values {
+					if distance := optimalStringAlignment(normalized, value); distance <= maxEdits {
+						expected[value] = float64(distance)
+					}
+				}
+
+				found := map[string]float64{}
+				for _, match := range fuzzyMatcherCore.SearchAutomaton(params) {
+					value := strings.TrimPrefix(match.Text, string(ft.Firstname)+":")
+					_, duplicate := found[value]
+					assert.False(t, duplicate, "%s found %s twice within %d edits", query, value, maxEdits)
+					found[value] = match.EditCount
+				}
+
+				assert.Equal(t, expected, found, "%s within %d edits", query, maxEdits)
+			}
+		}
+	})
+
+	t.Run("MatchesRecursiveEngine", func(t *testing.T) {
+		recursive := &fmc.FuzzyMatcherCore[fc.ExampleSource]{
+			CoreParams: ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{MaxEdits: 6, CorrectOcrMisreads: true},
+		}
+		require.NoError(t, recursive.Build(members))
+		automaton := newCore(t, ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{MaxEdits: 6, CorrectOcrMisreads: true})
+
+		tests := []struct {
+			name       string
+			firstname  string
+			surname    string
+			birthdate  string
+			expectedID int
+		}{
+			{"Exact", "Sarah", "Johnson", "1985-12-03", 2},
+			{"MissingLetter", "Sarah", "Jonson", "1985-12-03", 2},
+			{"WrongLetter", "Olivia", "Milker", "1993-07-25", 6},
+			{"Transposition", "Sarah", "Jonhson", "1985-12-03", 2},
+			{"OcrMisread", "Ernma", "Davis", "1988-03-10", 4},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				birthdate, err := time.Parse("2006-01-02", tt.birthdate)
+				require.NoError(t, err)
+
+				query := fc.ExampleSource{ID: 999, Firstname: tt.firstname, Surname: tt.surname, Birthdate: birthdate}
+
+				found, matches := recursive.SearchFuzzy(query)
+				require.True(t, found)
+				assert.Equal(t, tt.expectedID, matches[0].Entry.ID)
+
+				found, matches = automaton.SearchFuzzy(query)
+				require.True(t, found)
+				assert.Equal(t, tt.expectedID, matches[0].Entry.ID)
+			})
+		}
+	})
+
+	t.Run("Explain", func(t *testing.T) {
+		fuzzyMatcherCore := newCore(t, ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{MaxEdits: 6, CorrectOcrMisreads: true})
+
+		tests := []struct {
+			name      string
+			firstname string
+			edits     []ft.Edit
+		}{
+			{"Insertion", "Mchael", []ft.Edit{{Operation: ft.EditInsertion, Position: 1, To: "i"}}},
+			{"Skip", "Micchael", []ft.Edit{{Operation: ft.EditSkip, Position: 2, From: "c"}}},
+			{"Substitution", "Mxchael", []ft.Edit{{Operation: ft.EditSubstitution, Position: 1, From: "x", To: "i"}}},
+			{"Transposition", "Micheal", []ft.Edit{{Operation: ft.EditTransposition, Position: 4, From: "ea", To: "ae"}}},
+			{"OcrMultiChar", "Rnichael", []ft.Edit{{Operation: ft.EditOcrMulti, Position: 0, From: "rn", To: "m"}}},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				found, matches := fuzzyMatcherCore.SearchFuzzy(fc.ExampleSource{
+					ID:        999,
+					Firstname: tt.firstname,
+					Surname:   "Brown",
+					Birthdate: time.Date(1992, 8, 22, 0, 0, 0, 0, time.UTC),
+				}, ft.SearchOptions{Explain: true})
+				require.True(t, found)
+
+				firstname := matches[0].Explanation[ft.Firstname]
+				assert.Equal(t, "michael", firstname.Matched)
+				assert.Equal(t, tt.edits, firstname.Edits)
+			})
+		}
+	})
+
+	t.Run("ExtensionsAreEdits", func(t *testing.T) {
+		fuzzyMatcherCore := newCore(t, ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{MaxEdits: 6})
+
+		params := fuzzyMatcherCore.NewRecurseParameters(ft.Firstname, "mic", ft.FuzzyMatcherParameters{
+			MaxEdits: map[ft.Field]int{ft.Firstname: 2},
+		})
+		for _, match := range fuzzyMatcherCore.SearchAutomaton(params) {
+			assert.NotEqual(t, "firstname:michael", match.Text)
+		}
+	})
+
+	t.Run("UnknownEngine", func(t *testing.T) {
+		fuzzyMatcherCore := &fmc.FuzzyMatcherCore[fc.ExampleSource]{
+			CoreParams: ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{SearchEngine: "bk-tree"},
+		}
+		assert.Error(t, fuzzyMatcherCore.Build(members))
+	})
+}
+
+func BenchmarkFuzzyMatcherCore_SearchEngines(b *testing.B) {
+	members := loadTestData(b)
+
+	engines := []struct {
+		name   string
+		engine ft.SearchEngine
+	}{
+		{"Recursive", ft.RecursiveEngine},
+		{"Automaton", ft.AutomatonEngine},
+		{"AStar", ft.AStarEngine},
+	}
+
+	queries := []fc.ExampleSource{
+		{ID: 999, Firstname: "Jon", Surname: "Smith", Birthdate: time.Date(1990, 5, 15, 0, 0, 0, 0, time.UTC)},
+		{ID: 999, Firstname: "Micheal", Surname: "Jonson", Birthdate: time.Date(1985, 12, 3, 0, 0, 0, 0, time.UTC)},
+		{ID: 999, Firstname: "Ernma", Surname: "Davis", Birthdate: time.Date(1988, 3, 10, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, engine := range engines {
+		b.Run(engine.name, func(b *testing.B) {
+			fuzzyMatcherCore := &fmc.FuzzyMatcherCore[fc.ExampleSource]{
+				CoreParams: ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{
+					MaxEdits:           6,
+					CorrectOcrMisreads: true,
+					SearchEngine:       engine.engine,
+				},
+			}
+			require.NoError(b, fuzzyMatcherCore.Build(members))
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				for _, query := range queries {
+					fuzzyMatcherCore.SearchFuzzy(query)
+				}
+			}
+		})
+	}
+}
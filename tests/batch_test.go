@@ -0,0 +1,107 @@
+package fuzzymatchertests
+
+import (
+	"context"
+	"testing"
+
+	fc "github.com/oiamo123/fuzzy_matcher/fuzzy_classes"
+	fmc "github.com/oiamo123/fuzzy_matcher/fuzzy_matcher_core"
+	ft "github.com/oiamo123/fuzzy_matcher/fuzzy_types"
+
+	fm "github.com/oiamo123/fuzzy_matcher"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createBatchQueries returns a query for each member, every other one with a typo in the firstname
+func createBatchQueries(members []fc.ExampleSource) []fc.ExampleSource {
+	queries := make([]fc.ExampleSource, 0, len(members))
+	for i, member := range members {
+		query := member
+		query.ID = 1000 + i
+		if i%2 == 1 {
+			query.Firstname += "x"
+		}
+		queries = append(queries, query)
+	}
+	return queries
+}
+
+func TestFuzzyMatcher_SearchBatch(t *testing.T) {
+	members := loadTestData(t)
+
+	matcher := &fm.FuzzyMatcher[fc.ExampleSource]{}
+	matcher.Init(ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{MaxEdits: 6})
+	matcher.InsertEntries(members)
+
+	queries := createBatchQueries(members)
+
+	for _, workers := range []int{0, 1, 3, 64} {
+		t.Run("Workers", func(t *testing.T) {
+			results := matcher.SearchBatch(queries, ft.BatchOptions{Workers: workers})
+			require.Len(t, results, len(queries))
+
+			for i, query := range queries {
+				found, matches := matcher.Search(query)
+
+				require.NoError(t, results[i].Err)
+				assert.Equal(t, found, results[i].Found, "query %d", i)
+				assert.Equal(t, matchIDs(matches), matchIDs(results[i].Matches), "Results should line up with the input, query %d", i)
+			}
+		})
+	}
+
+	t.Run("SearchOptions", func(t *testing.T) {
+		results := matcher.SearchBatch(queries, ft.BatchOptions{SearchOptions: ft.SearchOptions{Limit: 1}})
+
+		for _, result := range results {
+			assert.LessOrEqual(t, len(result.Matches), 1)
+		}
+		assert.Equal(t, members[0].ID, results[0].Matches[0].Entry.ID)
+	})
+
+	t.Run("PerItemErrors", func(t *testing.T) {
+		// Each search gets its own budget, so every search is cut short
+		results := matcher.SearchBatch(queries, ft.BatchOptions{SearchOptions: ft.SearchOptions{MaxNodes: 1}})
+
+		for _, result := range results {
+			assert.ErrorIs(t, result.Err, fmc.ErrNodeBudgetExceeded)
+		}
+	})
+
+	t.Run("Cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		results := matcher.SearchBatchContext(ctx, queries, ft.BatchOptions{Workers: 2})
+		require.Len(t, results, len(queries))
+
+		for _, result := range results {
+			assert.False(t, result.Found)
+			assert.ErrorIs(t, result.Err, fmc.ErrSearchTruncated)
+			assert.ErrorIs(t, result.Err, context.Canceled)
+		}
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		assert.Empty(t, matcher.SearchBatch(nil))
+	})
+}
+
+func BenchmarkFuzzyMatcher_SearchBatch(b *testing.B) {
+	members := loadTestData(b)
+
+	matcher := &fm.FuzzyMatcher[fc.ExampleSource]{}
+	matcher.Init(ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{MaxEdits: 6})
+	matcher.InsertEntries(members)
+
+	queries := createBatchQueries(members)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		matcher.SearchBatch(queries)
+	}
+}
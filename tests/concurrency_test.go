@@ -0,0 +1,130 @@
+package fuzzymatchertests
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	fc "github.com/oiamo123/fuzzy_matcher/fuzzy_classes"
+	ft "github.com/oiamo123/fuzzy_matcher/fuzzy_types"
+
+	fm "github.com/oiamo123/fuzzy_matcher"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Run with `go test -race ./tests/...` to have the race detector check these
+func TestConcurrentSearchInsertRemove(t *testing.T) {
+	members := loadTestData(t)
+
+	matcher := &fm.FuzzyMatcher[fc.ExampleSource]{}
+	matcher.Init(ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{
+		MaxEdits:           6,
+		CorrectOcrMisreads: true,
+	})
+	matcher.InsertEntries(members)
+
+	query := fc.ExampleSource{
+		ID:        999,
+		Firstname: "Jon",
+		Surname:   "Smith",
+		Birthdate: time.Date(1990, 5, 15, 0, 0, 0, 0, time.UTC),
+	}
+
+	var wg sync.WaitGroup
+
+	// Readers
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				matcher.Search(query)
+			}
+		}()
+	}
+
+	// Writers re-inserting and removing everyone but John Smith
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 25; j++ {
+				matcher.RemoveEntries(members[1:])
+				matcher.InsertEntries(members[1:])
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	found, matches := matcher.Search(query)
+	require.True(t, found)
+	assert.Equal(t, 1, matches[0].Entry.ID)
+}
+
+func TestConcurrentSearchWithExpiry(t *testing.T) {
+	params := ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{
+		MaxEdits:      6,
+		UseExpiration: true,
+	}
+
+	matcher := &fm.FuzzyMatcher[fc.ExampleSource]{}
+	matcher.Init(params)
+
+	// Kept alive for the whole test
+	longLived := fc.ExampleSource{
+		ID:          1,
+		Firstname:   "John",
+		Surname:     "Smith",
+		Birthdate:   time.Date(1990, 5, 15, 0, 0, 0, 0, time.UTC),
+		EventEndUtc: time.Now().Add(24 * time.Hour),
+	}
+	matcher.InsertEntries([]fc.ExampleSource{longLived})
+
+	query := fc.ExampleSource{
+		ID:        999,
+		Firstname: "John",
+		Surname:   "Smith",
+		Birthdate: time.Date(1990, 5, 15, 0, 0, 0, 0, time.UTC),
+	}
+
+	var wg sync.WaitGroup
+
+	// Writers inserting entries that are already expired, so every search has something to sweep
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func(offset int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				matcher.InsertEntries([]fc.ExampleSource{{
+					ID:          100 + offset*1000 + j,
+					Firstname:   "Johnny",
+					Surname:     "Smithers",
+					Birthdate:   time.Date(1990, 5, 15, 0, 0, 0, 0, time.UTC),
+					EventEndUtc: time.Now().Add(-24 * time.Hour),
+				}})
+			}
+		}(i)
+	}
+
+	// Readers and explicit expiry sweeps
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				matcher.Search(query)
+				matcher.FuzzyMatcherCore.Clean()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	found, matches := matcher.Search(query)
+	require.True(t, found)
+	assert.Equal(t, 1, matches[0].Entry.ID)
+	assert.Equal(t, 3, matcher.FuzzyMatcherCore.ExpiryHeap.Len(), "Only the long lived entry's fields should be pending expiry")
+}
@@ -0,0 +1,127 @@
+package fuzzymatchertests
+
+import (
+	"testing"
+	"time"
+
+	fc "github.com/oiamo123/fuzzy_matcher/fuzzy_classes"
+	fmc "github.com/oiamo123/fuzzy_matcher/fuzzy_matcher_core"
+	ft "github.com/oiamo123/fuzzy_matcher/fuzzy_types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFuzzyMatcherCore_EditCosts(t *testing.T) {
+	members := loadWaveMembersTestData(t)
+	birthdate := time.Date(1990, 5, 15, 0, 0, 0, 0, time.UTC)
+
+	newCore := func(t *testing.T, maxEdits int, correctOcr bool, costs ft.EditCosts) *fmc.FuzzyMatcherCore[fc.ExampleSource] {
+		core := &fmc.FuzzyMatcherCore[fc.ExampleSource]{CoreParams: ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{
+			MaxEdits:           maxEdits,
+			CorrectOcrMisreads: correctOcr,
+			EditCosts:          costs,
+		}}
+		require.NoError(t, core.Build(members))
+		return core
+	}
+
+	// Two OCR misreads, one in each name
+	misread := fc.ExampleSource{ID: 999, Firstname: "J0hn", Surname: "Sm1th", Birthdate: birthdate}
+
+	t.Run("DefaultCosts", func(t *testing.T) {
+		found, _ := newCore(t, 1, true, ft.EditCosts{}).SearchFuzzy(misread)
+		assert.False(t, found, "Two edits are over a budget of 1")
+	})
+
+	t.Run("OperationCosts", func(t *testing.T) {
+		core := newCore(t, 1, true, ft.EditCosts{
+			Operations: map[ft.EditOperation]float64{ft.EditOcrSingle: 0.5},
+		})
+
+		found, matches := core.SearchFuzzy(misread, ft.SearchOptions{Explain: true})
+		require.True(t, found)
+		assert.Equal(t, 1, matches[0].Entry.ID)
+		assert.Equal(t, []ft.Edit{
+			{Operation: ft.EditOcrSingle, Position: 1, From: "0", To: "o"},
+		}, matches[0].Explanation[ft.Firstname].Edits)
+	})
+
+	t.Run("PairCosts", func(t *testing.T) {
+		// Pairs apply to any edit making the replacement, here plain substitutions as OCR corrections are off
+		core := newCore(t, 1, false, ft.EditCosts{
+			Pairs: map[ft.EditPair]float64{
+				{From: "0", To: "o"}: 0.25,
+				{From: "1", To: "i"}: 0.25,
+			},
+		})
+
+		found, matches := core.SearchFuzzy(misread, ft.SearchOptions{Explain: true})
+		require.True(t, found)
+		assert.Equal(t, 1, matches[0].Entry.ID)
+		assert.Equal(t, []ft.Edit{
+			{Operation: ft.EditSubstitution, Position: 2, From: "1", To: "i"},
+		}, matches[0].Explanation[ft.Surname].Edits)
+	})
+
+	t.Run("ExpensiveEdits", func(t *testing.T) {
+		core := newCore(t, 6, false, ft.EditCosts{
+			Operations: map[ft.EditOperation]float64{ft.EditSubstitution: 3},
+		})
+
+		// Surnames have a budget of 2, the substitution alone is over it
+		found, matches := core.SearchFuzzy(fc.ExampleSource{ID: 999, Firstname: "John", Surname: "Smyth", Birthdate: birthdate}, ft.SearchOptions{Explain: true})
+		require.True(t, found)
+		assert.Equal(t, []ft.Edit{
+			{Operation: ft.EditSkip, Position: 2, From: "y"},
+			{Operation: ft.EditInsertion, Position: 3, To: "i"},
+		}, matches[0].Explanation[ft.Surname].Edits)
+
+		// A skip and an insertion are cheaper than a substitution
+		found, matches = core.SearchFuzzy(fc.ExampleSource{ID: 999, Firstname: "Jahn", Surname: "Smith", Birthdate: birthdate}, ft.SearchOptions{Explain: true})
+		require.True(t, found)
+		assert.Equal(t, []ft.Edit{
+			{Operation: ft.EditSkip, Position: 1, From: "a"},
+			{Operation: ft.EditInsertion, Position: 2, To: "o"},
+		}, matches[0].Explanation[ft.Firstname].Edits)
+	})
+
+	t.Run("FractionalLastEdit", func(t *testing.T) {
+		// Both misreads at 0.25 fit in a firstname budget of 1
+		core := newCore(t, 1, true, ft.EditCosts{
+			Operations: map[ft.EditOperation]float64{ft.EditOcrSingle: 0.25},
+		})
+
+		found, matches := core.SearchFuzzy(adjustedSource{
+			ExampleSource: fc.ExampleSource{ID: 999, Firstname: "M1cha3l", Surname: "Brown", Birthdate: time.Date(1992, 8, 22, 0, 0, 0, 0, time.UTC)},
+			Adjust:        withMaxEdits(ft.Firstname, 1),
+		})
+		require.True(t, found)
+		assert.Equal(t, 3, matches[0].Entry.ID)
+
+		params := ft.RecurseParameters{MaxEdits: 1}
+		assert.True(t, params.LastEdit(1))
+		assert.False(t, params.LastEdit(0.25), "Several edits at 0.25 fit in the last unit")
+
+		// Visited keys keep fractional costs apart
+		assert.NotEqual(t, core.MakeKey(1, 0, 0, 'a'), core.MakeKey(1, 0.5, 0, 'a'))
+	})
+
+	t.Run("Cost", func(t *testing.T) {
+		costs := ft.EditCosts{
+			Operations: map[ft.EditOperation]float64{ft.EditOcrSingle: 0.5},
+			Pairs:      map[ft.EditPair]float64{{From: "0", To: "o"}: 0.25},
+		}
+
+		assert.Equal(t, 0.25, costs.Cost(ft.EditOcrSingle, "0", "o"), "Pairs are used over operations")
+		assert.Equal(t, 0.5, costs.Cost(ft.EditOcrSingle, "1", "i"))
+		assert.Equal(t, 1.0, costs.Cost(ft.EditSkip, "a", ""))
+		assert.True(t, ft.ExceedsEdits(1.5, 1))
+
+		total := 0.0
+		for _, cost := range []float64{0.55, 1.6, 0.85} {
+			total += cost
+		}
+		assert.False(t, ft.ExceedsEdits(total, 3), "Rounding shouldn't push a sum over the budget")
+	})
+}
@@ -47,7 +47,7 @@ func (tm TestMember) ToExampleSource() fc.ExampleSource {
 }
 
 // loadTestData loads wave members from the JSON test file
-func loadTestData(t *testing.T) []fc.ExampleSource {
+func loadTestData(t testing.TB) []fc.ExampleSource {
 	data, err := os.ReadFile("test_data/example_members.json")
 	require.NoError(t, err, "Failed to read test data file")
 
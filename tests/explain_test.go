@@ -0,0 +1,125 @@
+package fuzzymatchertests
+
+import (
+	"testing"
+	"time"
+
+	fc "github.com/oiamo123/fuzzy_matcher/fuzzy_classes"
+	fmc "github.com/oiamo123/fuzzy_matcher/fuzzy_matcher_core"
+	ft "github.com/oiamo123/fuzzy_matcher/fuzzy_types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchOptions_Explain(t *testing.T) {
+	members := []fc.ExampleSource{
+		{ID: 1, Firstname: "Michael", Surname: "Smith", Birthdate: time.Date(1990, 5, 15, 0, 0, 0, 0, time.UTC)},
+		{ID: 2, Firstname: "Smith", Surname: "Miller", Birthdate: time.Date(1985, 1, 2, 0, 0, 0, 0, time.UTC)},
+	}
+
+	fuzzyMatcherCore := &fmc.FuzzyMatcherCore[fc.ExampleSource]{
+		CoreParams: ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{
+			MaxEdits:           6,
+			CorrectOcrMisreads: true,
+		},
+	}
+	require.NoError(t, fuzzyMatcherCore.Build(members))
+
+	search := func(t *testing.T, query fc.ExampleSource, opts ...ft.SearchOptions) ft.FuzzyMatch[fc.ExampleSource] {
+		found, matches := fuzzyMatcherCore.SearchFuzzy(query, opts...)
+		require.True(t, found)
+		return matches[0]
+	}
+
+	t.Run("Disabled", func(t *testing.T) {
+		match := search(t, fc.ExampleSource{ID: 999, Firstname: "Micheal", Surname: "Smith", Birthdate: members[0].Birthdate})
+		assert.Nil(t, match.Explanation)
+	})
+
+	t.Run("SkipsAndExtensions", func(t *testing.T) {
+		match := search(t, fc.ExampleSource{ID: 999, Firstname: "Michal", Surname: "Smith", Birthdate: members[0].Birthdate}, ft.SearchOptions{Explain: true})
+		require.Len(t, match.Explanation, 3)
+
+		firstname := match.Explanation[ft.Firstname]
+		assert.Equal(t, "michal", firstname.Query)
+		assert.Equal(t, "michal", firstname.Searched)
+		assert.Equal(t, "michael", firstname.Matched)
+		assert.Equal(t, ft.JaroWinkler, firstname.Method)
+		assert.Equal(t, []ft.Edit{
+			{Operation: ft.EditSkip, Position: 5, From: "l"},
+			{Operation: ft.EditExtension, Position: 6, To: "e"},
+			{Operation: ft.EditExtension, Position: 7, To: "l"},
+		}, firstname.Edits)
+
+		surname := match.Explanation[ft.Surname]
+		assert.Empty(t, surname.Edits)
+		assert.InDelta(t, 1.0, surname.Similarity, 0.0001)
+		assert.InDelta(t, 0.4, surname.Contribution, 0.0001)
+	})
+
+	t.Run("Transposition", func(t *testing.T) {
+		match := search(t, fc.ExampleSource{ID: 999, Firstname: "Micheal", Surname: "Smith", Birthdate: members[0].Birthdate}, ft.SearchOptions{Explain: true})
+
+		assert.Equal(t, []ft.Edit{
+			{Operation: ft.EditTransposition, Position: 4, From: "ea", To: "ae"},
+		}, match.Explanation[ft.Firstname].Edits)
+	})
+
+	t.Run("Substitution", func(t *testing.T) {
+		match := search(t, fc.ExampleSource{ID: 999, Firstname: "Mxchael", Surname: "Smith", Birthdate: members[0].Birthdate}, ft.SearchOptions{Explain: true})
+
+		assert.Equal(t, []ft.Edit{
+			{Operation: ft.EditSubstitution, Position: 1, From: "x", To: "i"},
+		}, match.Explanation[ft.Firstname].Edits)
+	})
+
+	t.Run("Insertion", func(t *testing.T) {
+		match := search(t, fc.ExampleSource{ID: 999, Firstname: "Mchael", Surname: "Smth", Birthdate: members[0].Birthdate}, ft.SearchOptions{Explain: true})
+
+		assert.Equal(t, []ft.Edit{
+			{Operation: ft.EditInsertion, Position: 1, To: "i"},
+		}, match.Explanation[ft.Firstname].Edits)
+		assert.Equal(t, []ft.Edit{
+			{Operation: ft.EditInsertion, Position: 2, To: "i"},
+		}, match.Explanation[ft.Surname].Edits)
+	})
+
+	t.Run("OcrMultiChar", func(t *testing.T) {
+		match := search(t, fc.ExampleSource{ID: 999, Firstname: "Srnith", Surname: "Miller", Birthdate: members[1].Birthdate}, ft.SearchOptions{Explain: true})
+		assert.Equal(t, 2, match.Entry.ID)
+
+		assert.Equal(t, []ft.Edit{
+			{Operation: ft.EditOcrMulti, Position: 1, From: "rn", To: "m"},
+		}, match.Explanation[ft.Firstname].Edits)
+	})
+
+	t.Run("ContributionsAddUpToScore", func(t *testing.T) {
+		match := search(t, fc.ExampleSource{ID: 999, Firstname: "Micheal", Surname: "Smith", Birthdate: members[0].Birthdate}, ft.SearchOptions{Explain: true})
+
+		var total float64
+		for _, explanation := range match.Explanation {
+			assert.InDelta(t, explanation.Weight*explanation.Similarity, explanation.Contribution, 0.0001)
+			total += explanation.Contribution
+		}
+		assert.InDelta(t, match.Score, total, 0.0001)
+	})
+
+	t.Run("Synonym", func(t *testing.T) {
+		synonymCore := createSynonymCore(t, loadNicknameDictionary(t), 0)
+
+		found, matches := synonymCore.SearchFuzzy(fc.ExampleSource{
+			ID:        999,
+			Firstname: "Bill",
+			Surname:   "Moore",
+			Birthdate: time.Date(1987, 11, 8, 0, 0, 0, 0, time.UTC),
+		}, ft.SearchOptions{Explain: true})
+		require.True(t, found)
+
+		firstname := matches[0].Explanation[ft.Firstname]
+		assert.Equal(t, "bill", firstname.Query)
+		assert.Equal(t, "william", firstname.Searched)
+		assert.Equal(t, "william", firstname.Synonym)
+		assert.InDelta(t, 0.9, firstname.Similarity, 0.0001)
+	})
+}
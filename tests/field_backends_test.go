@@ -0,0 +1,433 @@
+package fuzzymatchertests
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	fc "github.com/oiamo123/fuzzy_matcher/fuzzy_classes"
+	fmc "github.com/oiamo123/fuzzy_matcher/fuzzy_matcher_core"
+	ft "github.com/oiamo123/fuzzy_matcher/fuzzy_types"
+
+	"github.com/antzucaro/matchr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFuzzyMatcherCore_SymSpellBackend(t *testing.T) {
+	members := loadTestData(t)
+
+	backends := map[ft.Field]ft.BackendKind{
+		ft.Firstname: ft.SymSpellBackend,
+		ft.Surname:   ft.SymSpellBackend,
+		ft.Birthdate: ft.SymSpellBackend,
+	}
+
+	newCore := func(t *testing.T, params ft.FuzzyMatcherCoreParameters[fc.ExampleSource], members []fc.ExampleSource) *fmc.FuzzyMatcherCore[fc.ExampleSource] {
+		params.MaxEdits = 6
+		core := &fmc.FuzzyMatcherCore[fc.ExampleSource]{CoreParams: params}
+		require.NoError(t, core.Build(members))
+		return core
+	}
+
+	queries := []fc.ExampleSource{
+		{ID: 999, Firstname: "John", Surname: "Smith", Birthdate: time.Date(1990, 5, 15, 0, 0, 0, 0, time.UTC)},
+		{ID: 999, Firstname: "Jon", Surname: "Smtih", Birthdate: time.Date(1990, 5, 15, 0, 0, 0, 0, time.UTC)},
+		{ID: 999, Firstname: "Sarha", Surname: "Jonson", Birthdate: time.Date(1985, 12, 3, 0, 0, 0, 0, time.UTC)},
+		{ID: 999, Firstname: "Olivia", Surname: "Milker", Birthdate: time.Date(1993, 7, 52, 0, 0, 0, 0, time.UTC)},
+	}
+
+	t.Run("MatchesAutomatonEngine", func(t *testing.T) {
+		// Both count every edit, including characters past the end of the query, and find each value once at its cheapest
+		automaton := newCore(t, ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{SearchEngine: ft.AutomatonEngine}, members)
+		symSpell := newCore(t, ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{FieldBackends: backends}, members)
+
+		for _, query := range queries {
+			options := ft.SearchOptions{Limit: -1, Explain: true}
+			expectedFound, expected := automaton.SearchFuzzy(query, options)
+			found, matches := symSpell.SearchFuzzy(query, options)
+
+			require.Equal(t, expectedFound, found, query.Firstname)
+			require.Len(t, matches, len(expected), query.Firstname)
+			for i := range expected {
+				assert.Equal(t, expected[i].Entry.ID, matches[i].Entry.ID, query.Firstname)
+				assert.InDelta(t, expected[i].Score, matches[i].Score, 0.0001, query.Firstname)
+			}
+		}
+	})
+
+	t.Run("Explain", func(t *testing.T) {
+		core := newCore(t, ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{FieldBackends: backends}, members)
+
+		found, matches := core.SearchFuzzy(queries[1], ft.SearchOptions{Explain: true})
+		require.True(t, found)
+		assert.Equal(t, 1, matches[0].Entry.ID)
+
+		assert.Equal(t, []ft.Edit{
+			{Operation: ft.EditInsertion, Position: 2, To: "h"},
+		}, matches[0].Explanation[ft.Firstname].Edits)
+		assert.Equal(t, []ft.Edit{
+			{Operation: ft.EditTransposition, Position: 2, From: "ti", To: "it"},
+		}, matches[0].Explanation[ft.Surname].Edits)
+	})
+
+	t.Run("MaxEditsCapped", func(t *testing.T) {
+		// Surnames allow two edits, an index built for one can only find values one edit away
+		core := newCore(t, ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{FieldBackends: backends, SymSpellMaxEdits: 1}, members)
+
+		found, matches := core.SearchFuzzy(queries[1])
+		require.True(t, found)
+		assert.Equal(t, 1, matches[0].Entry.ID)
+
+		found, _ = core.SearchFuzzy(fc.ExampleSource{ID: 999, Firstname: "John", Surname: "Smyht", Birthdate: queries[0].Birthdate})
+		assert.False(t, found)
+	})
+
+	t.Run("FractionalCosts", func(t *testing.T) {
+		// A surname budget of 2 holds four edits at 0.5, the index has to be built for all of them
+		costs := ft.EditCosts{Operations: map[ft.EditOperation]float64{
+			ft.EditSkip:          0.5,
+			ft.EditInsertion:     0.5,
+			ft.EditSubstitution:  0.5,
+			ft.EditTransposition: 0.5,
+		}}
+		automaton := newCore(t, ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{SearchEngine: ft.AutomatonEngine, EditCosts: costs}, members)
+		symSpell := newCore(t, ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{FieldBackends: map[ft.Field]ft.BackendKind{ft.Surname: ft.SymSpellBackend}, EditCosts: costs}, members)
+
+		// Each surname is at least three edits from the member's, more than a budget of 2 holds at the default cost
+		tests := []struct {
+			surname    string
+			expectedID int
+		}{
+			{"Snyht", 1},
+			{"Jhnosm", 2},
+			{"Bwrno", 3},
+		}
+
+		for _, tt := range tests {
+			query := fc.ExampleSource{ID: 999, Firstname: members[tt.expectedID-1].Firstname, Surname: tt.surname, Birthdate: members[tt.expectedID-1].Birthdate}
+			options := ft.SearchOptions{Limit: -1, Explain: true}
+
+			explanation := func(matches []ft.FuzzyMatch[fc.ExampleSource]) ft.FieldExplanation {
+				for _, match := range matches {
+					if match.Entry.ID == tt.expectedID {
+						return match.Explanation[ft.Surname]
+					}
+				}
+				return ft.FieldExplanation{}
+			}
+
+			_, matches := automaton.SearchFuzzy(adjustedSource{ExampleSource: query, Adjust: withMinDistance(ft.Surname, 0)}, options)
+			expected := explanation(matches)
+			require.GreaterOrEqual(t, len(expected.Edits), 3, tt.surname)
+
+			_, matches = symSpell.SearchFuzzy(adjustedSource{ExampleSource: query, Adjust: withMinDistance(ft.Surname, 0)}, options)
+			assert.Equal(t, expected.Matched, explanation(matches).Matched, tt.surname)
+		}
+
+		core := &fmc.FuzzyMatcherCore[fc.ExampleSource]{CoreParams: ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{
+			FieldBackends: backends,
+			EditCosts:     ft.EditCosts{Operations: map[ft.EditOperation]float64{ft.EditSkip: 0}},
+		}}
+		assert.Error(t, core.Build(members), "Free edits can't be indexed")
+
+		// The index is never searched with OCR misreads, so their costs don't make it deeper
+		ocrCosts := ft.EditCosts{Operations: map[ft.EditOperation]float64{ft.EditOcrSingle: 0.25, ft.EditOcrMulti: 0.25}}
+		newCore(t, ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{FieldBackends: backends, EditCosts: ocrCosts}, members)
+
+		// A budget of 2 at 0.25 would need 8 deletes of every value
+		core = &fmc.FuzzyMatcherCore[fc.ExampleSource]{CoreParams: ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{
+			FieldBackends: backends,
+			EditCosts:     ft.EditCosts{Pairs: map[ft.EditPair]float64{{From: "y", To: "i"}: 0.25}},
+		}}
+		assert.Error(t, core.Build(members), "Deep indexes are rejected")
+	})
+
+	t.Run("RemoveEntries", func(t *testing.T) {
+		core := newCore(t, ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{FieldBackends: backends}, members)
+		core.RemoveEntries(members[:1])
+
+		found, _ := core.SearchFuzzy(queries[0])
+		assert.False(t, found)
+	})
+
+	t.Run("RemoveByID", func(t *testing.T) {
+		core := newCore(t, ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{FieldBackends: backends}, members)
+		core.RemoveByID(members[0].ID)
+
+		found, _ := core.SearchFuzzy(queries[0])
+		assert.False(t, found)
+	})
+
+	t.Run("Upsert", func(t *testing.T) {
+		core := newCore(t, ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{FieldBackends: backends}, members)
+
+		renamed := members[0]
+		renamed.Surname = "Smyth"
+		require.NoError(t, core.Upsert([]fc.ExampleSource{renamed}))
+
+		// The old surname is gone from the index, so "Smith" is now one edit away
+		found, matches := core.SearchFuzzy(queries[0], ft.SearchOptions{TieBreak: ft.TieBreakEdits})
+		require.True(t, found)
+		assert.Equal(t, renamed, matches[0].Entry)
+		assert.Less(t, matches[0].Score, 1.0)
+	})
+
+	t.Run("Clean", func(t *testing.T) {
+		expiring := make([]fc.ExampleSource, len(members))
+		copy(expiring, members)
+		for i := range expiring {
+			expiring[i].EventEndUtc = time.Now().Add(time.Hour)
+		}
+		expiring[0].EventEndUtc = time.Now().Add(-24 * time.Hour) // Entries expire 12 hours after the event
+
+		core := newCore(t, ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{FieldBackends: backends, UseExpiration: true}, expiring)
+
+		// A backend still holding the expired ID would match it without an entry
+		found, _ := core.SearchFuzzy(queries[0])
+		assert.False(t, found)
+	})
+
+	t.Run("SaveAndLoad", func(t *testing.T) {
+		params := ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{FieldBackends: backends}
+		original := newCore(t, params, members)
+
+		var snapshot bytes.Buffer
+		require.NoError(t, original.Save(&snapshot))
+
+		params.MaxEdits = 6
+		restored := &fmc.FuzzyMatcherCore[fc.ExampleSource]{CoreParams: params}
+		require.NoError(t, restored.Load(&snapshot))
+
+		for _, query := range queries {
+			expectedFound, expected := original.SearchFuzzy(query)
+			found, matches := restored.SearchFuzzy(query)
+			require.Equal(t, expectedFound, found)
+			require.Len(t, matches, len(expected))
+			for i := range expected {
+				assert.Equal(t, expected[i].Entry, matches[i].Entry)
+				assert.InDelta(t, expected[i].Score, matches[i].Score, 0.0001)
+			}
+		}
+	})
+
+	t.Run("UnknownBackend", func(t *testing.T) {
+		core := &fmc.FuzzyMatcherCore[fc.ExampleSource]{
+			CoreParams: ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{
+				FieldBackends: map[ft.Field]ft.BackendKind{ft.Surname: "suffix-array"},
+			},
+		}
+		assert.Error(t, core.Build(members))
+	})
+}
+
+func TestFuzzyMatcherCore_BKTreeBackend(t *testing.T) {
+	members := loadTestData(t)
+
+	backends := map[ft.Field]ft.BackendKind{
+		ft.Firstname: ft.BKTreeBackend,
+		ft.Surname:   ft.BKTreeBackend,
+		ft.Birthdate: ft.BKTreeBackend,
+	}
+
+	newCore := func(t *testing.T) *fmc.FuzzyMatcherCore[fc.ExampleSource] {
+		core := &fmc.FuzzyMatcherCore[fc.ExampleSource]{CoreParams: ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{FieldBackends: backends}}
+		require.NoError(t, core.Build(members))
+		return core
+	}
+
+	queries := []fc.ExampleSource{
+		{ID: 999, Firstname: "John", Surname: "Smith", Birthdate: time.Date(1990, 5, 15, 0, 0, 0, 0, time.UTC)},
+		{ID: 999, Firstname: "Jon", Surname: "Smtih", Birthdate: time.Date(1990, 5, 15, 0, 0, 0, 0, time.UTC)},
+		{ID: 999, Firstname: "Sarha", Surname: "Jonson", Birthdate: time.Date(1985, 12, 3, 0, 0, 0, 0, time.UTC)},
+		{ID: 999, Firstname: "Emily", Surname: "Davies", Birthdate: time.Date(1988, 3, 10, 0, 0, 0, 0, time.UTC)},
+	}
+
+	t.Run("FindsEveryValueWithinMinDistance", func(t *testing.T) {
+		// Levenshtein scaled by a constant keeps to the triangle inequality, so nothing can be pruned that should match
+		core := newCore(t)
+		require.NoError(t, core.RegisterMetric(ft.JaroWinkler, ft.SimilarityFunc(func(s1, s2 string) float64 {
+			return 1 - float64(matchr.Levenshtein(s1, s2))/20
+		})))
+
+		params := members[0].GetSearchParameters()
+		for _, query := range queries {
+			firstname := core.NormalizeFieldFor(ft.Firstname, query.Firstname)
+			surname := core.NormalizeFieldFor(ft.Surname, query.Surname)
+
+			// Birthdates use the default method, so every birthdate is similar enough
+			expected := []int{}
+			for _, member := range members {
+				if core.CalculateSimilarity(firstname, core.NormalizeFieldFor(ft.Firstname, member.Firstname), ft.JaroWinkler) >= params.MinDistances[ft.Firstname] &&
+					core.CalculateSimilarity(surname, core.NormalizeFieldFor(ft.Surname, member.Surname), ft.JaroWinkler) >= params.MinDistances[ft.Surname] {
+					expected = append(expected, member.ID)
+				}
+			}
+
+			found, matches := core.SearchFuzzy(query, ft.SearchOptions{Limit: -1})
+			ids := []int{}
+			for _, match := range matches {
+				ids = append(ids, match.Entry.ID)
+			}
+
+			assert.Equal(t, len(expected) > 0, found, query.Firstname)
+			assert.ElementsMatch(t, expected, ids, query.Firstname)
+		}
+	})
+
+	t.Run("JaroWinkler", func(t *testing.T) {
+		core := newCore(t)
+
+		found, matches := core.SearchFuzzy(queries[1], ft.SearchOptions{Explain: true})
+		require.True(t, found)
+		assert.Equal(t, 1, matches[0].Entry.ID)
+
+		// Values are found by similarity, so there are no edits to explain
+		explanation := matches[0].Explanation[ft.Surname]
+		assert.Equal(t, "smith", explanation.Matched)
+		assert.Empty(t, explanation.Edits)
+		assert.GreaterOrEqual(t, explanation.Similarity, 0.9)
+	})
+
+	t.Run("RemoveAndInsertAgain", func(t *testing.T) {
+		core := newCore(t)
+
+		// The trees are built by the first search, after the value was removed, so it has to be added to them
+		core.RemoveByID(members[0].ID)
+		found, _ := core.SearchFuzzy(queries[0])
+		assert.False(t, found)
+
+		require.NoError(t, core.Upsert(members[:1]))
+		found, matches := core.SearchFuzzy(queries[0])
+		require.True(t, found)
+		assert.Equal(t, members[0], matches[0].Entry)
+	})
+
+	t.Run("RemovedValues", func(t *testing.T) {
+		core := newCore(t)
+		found, _ := core.SearchFuzzy(queries[0])
+		require.True(t, found)
+
+		ids := []int{}
+		for _, member := range members[1:] {
+			ids = append(ids, member.ID)
+		}
+		core.RemoveByID(ids...)
+
+		// Only John Smith's three values are left, removed values don't use up the budget
+		found, matches, err := core.SearchContext(context.Background(), queries[0], ft.SearchOptions{MaxNodes: 3})
+		require.NoError(t, err)
+		require.True(t, found)
+		assert.Equal(t, members[0], matches[0].Entry)
+	})
+
+	t.Run("RegisterMetricAfterSearch", func(t *testing.T) {
+		core := newCore(t)
+		found, _ := core.SearchFuzzy(queries[0])
+		require.True(t, found)
+
+		// Trees built with Jaro-Winkler would prune with its distances, the new metric finds every value
+		require.NoError(t, core.RegisterMetric(ft.JaroWinkler, ft.SimilarityFunc(func(s1, s2 string) float64 {
+			return 1
+		})))
+
+		_, matches := core.SearchFuzzy(fc.ExampleSource{ID: 999, Firstname: "Zed", Surname: "Zulu", Birthdate: queries[0].Birthdate}, ft.SearchOptions{Limit: -1})
+		assert.Len(t, matches, len(members))
+	})
+
+	t.Run("SaveAndLoad", func(t *testing.T) {
+		original := newCore(t)
+
+		var snapshot bytes.Buffer
+		require.NoError(t, original.Save(&snapshot))
+
+		restored := &fmc.FuzzyMatcherCore[fc.ExampleSource]{CoreParams: original.CoreParams}
+		require.NoError(t, restored.Load(&snapshot))
+
+		for _, query := range queries {
+			expectedFound, expected := original.SearchFuzzy(query)
+			found, matches := restored.SearchFuzzy(query)
+			require.Equal(t, expectedFound, found)
+			require.Len(t, matches, len(expected))
+			for i := range expected {
+				assert.Equal(t, expected[i].Entry, matches[i].Entry)
+			}
+		}
+	})
+}
+
+func TestFuzzyMatcherCore_NGramBackend(t *testing.T) {
+	patients := []fc.BenchmarkSource{
+		{ID: 1, Name: "Bobby Jackson", DateOfAdmission: "2024-01-31", Hospital: "Sons and Miller"},
+		{ID: 2, Name: "Leslie Terry", DateOfAdmission: "2019-08-20", Hospital: "Kim Inc"},
+		{ID: 3, Name: "Danny Smith", DateOfAdmission: "2022-09-22", Hospital: "Cook PLC"},
+		{ID: 4, Name: "Andrew Watts", DateOfAdmission: "2020-11-18", Hospital: "Hernandez Rogers and Vang"},
+		{ID: 5, Name: "Adrienne Bell", DateOfAdmission: "2022-09-19", Hospital: "White Smith Inc"},
+		{ID: 6, Name: "Adrienne Bell", DateOfAdmission: "2022-09-19", Hospital: "White Smith Ltd"},
+	}
+
+	newCore := func(t *testing.T, params ft.FuzzyMatcherCoreParameters[fc.BenchmarkSource]) *fmc.FuzzyMatcherCore[fc.BenchmarkSource] {
+		core := &fmc.FuzzyMatcherCore[fc.BenchmarkSource]{CoreParams: params}
+		require.NoError(t, core.Build(patients))
+		return core
+	}
+	backends := map[ft.Field]ft.BackendKind{fc.Hospital: ft.NGramBackend}
+
+	// Hospitals allow no edits, so the trie can't find a typo, least of all in the first character
+	typo := fc.BenchmarkSource{ID: 999, Name: "Andrew Watts", DateOfAdmission: "2020-11-18", Hospital: "Bernandez Rogers and Vang"}
+
+	t.Run("FirstCharacterTypo", func(t *testing.T) {
+		trie := newCore(t, ft.FuzzyMatcherCoreParameters[fc.BenchmarkSource]{})
+		found, _ := trie.SearchFuzzy(typo)
+		assert.False(t, found)
+
+		core := newCore(t, ft.FuzzyMatcherCoreParameters[fc.BenchmarkSource]{FieldBackends: backends})
+		found, matches := core.SearchFuzzy(typo, ft.SearchOptions{Explain: true})
+		require.True(t, found)
+		assert.Equal(t, 4, matches[0].Entry.ID)
+		assert.Equal(t, "hernandezrogersandvang", matches[0].Explanation[fc.Hospital].Matched)
+		assert.Empty(t, matches[0].Explanation[fc.Hospital].Edits)
+	})
+
+	t.Run("MinOverlap", func(t *testing.T) {
+		core := newCore(t, ft.FuzzyMatcherCoreParameters[fc.BenchmarkSource]{FieldBackends: backends, NGramMinOverlap: 1})
+
+		found, _ := core.SearchFuzzy(typo)
+		assert.False(t, found)
+
+		exact := typo
+		exact.Hospital = patients[3].Hospital
+		found, _ = core.SearchFuzzy(exact)
+		assert.True(t, found)
+	})
+
+	t.Run("MaxFieldMatches", func(t *testing.T) {
+		core := newCore(t, ft.FuzzyMatcherCoreParameters[fc.BenchmarkSource]{FieldBackends: backends})
+		query := patients[4]
+		query.ID = 999
+
+		// "whitesmithltd" shares half of the n-grams of "whitesmithinc"
+		_, matches := core.SearchFuzzy(query, ft.SearchOptions{Limit: -1})
+		assert.Len(t, matches, 2)
+
+		_, matches = core.SearchFuzzy(query, ft.SearchOptions{Limit: -1, MaxFieldMatches: 1})
+		require.Len(t, matches, 1)
+		assert.Equal(t, 5, matches[0].Entry.ID)
+	})
+
+	t.Run("RemoveByID", func(t *testing.T) {
+		core := newCore(t, ft.FuzzyMatcherCoreParameters[fc.BenchmarkSource]{FieldBackends: backends})
+		core.RemoveByID(patients[3].ID)
+
+		found, _ := core.SearchFuzzy(typo)
+		assert.False(t, found)
+	})
+
+	t.Run("InvalidMinOverlap", func(t *testing.T) {
+		core := &fmc.FuzzyMatcherCore[fc.BenchmarkSource]{
+			CoreParams: ft.FuzzyMatcherCoreParameters[fc.BenchmarkSource]{FieldBackends: backends, NGramMinOverlap: 1.5},
+		}
+		assert.Error(t, core.Build(patients))
+	})
+}
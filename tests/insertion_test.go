@@ -0,0 +1,111 @@
+package fuzzymatchertests
+
+import (
+	"testing"
+	"time"
+
+	fc "github.com/oiamo123/fuzzy_matcher/fuzzy_classes"
+	fmc "github.com/oiamo123/fuzzy_matcher/fuzzy_matcher_core"
+	ft "github.com/oiamo123/fuzzy_matcher/fuzzy_types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFuzzyMatcherCore_InsertionsAndSubstitutions(t *testing.T) {
+	members := loadWaveMembersTestData(t)
+
+	// OCR corrections are off, so none of these can be found through an OCR substitution
+	fuzzyMatcherCore := &fmc.FuzzyMatcherCore[fc.ExampleSource]{
+		CoreParams: ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{MaxEdits: 6},
+	}
+	require.NoError(t, fuzzyMatcherCore.Build(members))
+
+	tests := []struct {
+		name       string
+		firstname  string
+		surname    string
+		birthdate  string
+		expectedID int
+		operation  ft.EditOperation
+	}{
+		{"MissingLetter", "Sarah", "Jonson", "1985-12-03", 2, ft.EditInsertion},
+		{"MissingLetters", "Olivia", "Mler", "1993-07-25", 6, ft.EditInsertion},
+		{"MissingVowel", "Benjamin", "Andrson", "1989-09-14", 9, ft.EditInsertion},
+		{"WrongLetter", "Sarah", "Jahnson", "1985-12-03", 2, ft.EditSubstitution},
+		{"WrongLetterMidWord", "Olivia", "Milker", "1993-07-25", 6, ft.EditSubstitution},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			birthdate, err := time.Parse("2006-01-02", tt.birthdate)
+			require.NoError(t, err)
+
+			found, matches := fuzzyMatcherCore.SearchFuzzy(fc.ExampleSource{
+				ID:        999,
+				Firstname: tt.firstname,
+				Surname:   tt.surname,
+				Birthdate: birthdate,
+			}, ft.SearchOptions{Explain: true})
+			require.True(t, found)
+			assert.Equal(t, tt.expectedID, matches[0].Entry.ID)
+
+			operations := []ft.EditOperation{}
+			for _, edit := range matches[0].Explanation[ft.Surname].Edits {
+				operations = append(operations, edit.Operation)
+			}
+			assert.Contains(t, operations, tt.operation)
+		})
+	}
+
+	t.Run("SingleEdit", func(t *testing.T) {
+		// The edit is the whole budget and the next character isn't in the trie, so it has to be tried there
+		singleEdit := &fmc.FuzzyMatcherCore[fc.ExampleSource]{
+			CoreParams: ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{MaxEdits: 1},
+		}
+		require.NoError(t, singleEdit.Build(members))
+
+		tests := []struct {
+			name       string
+			firstname  string
+			surname    string
+			birthdate  string
+			expectedID int
+			operation  ft.EditOperation
+		}{
+			{"MissingLetter", "Sarah", "Jonson", "1985-12-03", 2, ft.EditInsertion},
+			{"WrongVowel", "John", "Smoth", "1990-05-15", 1, ft.EditSubstitution},
+			{"WrongConsonant", "John", "Snith", "1990-05-15", 1, ft.EditSubstitution},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				birthdate, err := time.Parse("2006-01-02", tt.birthdate)
+				require.NoError(t, err)
+
+				found, matches := singleEdit.SearchFuzzy(adjustedSource{
+					ExampleSource: fc.ExampleSource{ID: 999, Firstname: tt.firstname, Surname: tt.surname, Birthdate: birthdate},
+					Adjust:        withMaxEdits(ft.Surname, 1),
+				}, ft.SearchOptions{Explain: true})
+				require.True(t, found)
+				assert.Equal(t, tt.expectedID, matches[0].Entry.ID)
+
+				edits := matches[0].Explanation[ft.Surname].Edits
+				require.Len(t, edits, 1)
+				assert.Equal(t, tt.operation, edits[0].Operation)
+			})
+		}
+	})
+
+	t.Run("WithinMaxEdits", func(t *testing.T) {
+		// Surnames allow two edits by default, "Mr" is three insertions away from "Miller"
+		found, matches := fuzzyMatcherCore.SearchFuzzy(fc.ExampleSource{
+			ID:        999,
+			Firstname: "Olivia",
+			Surname:   "Mr",
+			Birthdate: time.Date(1993, 7, 25, 0, 0, 0, 0, time.UTC),
+		})
+		assert.False(t, found)
+		assert.Empty(t, matches)
+	})
+}
@@ -0,0 +1,127 @@
+package fuzzymatchertests
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	fc "github.com/oiamo123/fuzzy_matcher/fuzzy_classes"
+	fmc "github.com/oiamo123/fuzzy_matcher/fuzzy_matcher_core"
+	ft "github.com/oiamo123/fuzzy_matcher/fuzzy_types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// adjustedSource searches with the example source's parameters after applying Adjust
+type adjustedSource struct {
+	fc.ExampleSource
+	Adjust func(params *ft.FuzzyMatcherParameters)
+}
+
+func (s adjustedSource) GetSearchParameters() ft.FuzzyMatcherParameters {
+	params := s.ExampleSource.GetSearchParameters()
+	if s.Adjust != nil {
+		s.Adjust(&params)
+	}
+	return params
+}
+
+// withMethod returns an Adjust func that uses method for field
+func withMethod(field ft.Field, method ft.CalculationMethod) func(params *ft.FuzzyMatcherParameters) {
+	return func(params *ft.FuzzyMatcherParameters) {
+		params.CalculationMethods[field] = method
+	}
+}
+
+// withMaxEdits returns an Adjust func that allows edits edits in field
+func withMaxEdits(field ft.Field, edits int) func(params *ft.FuzzyMatcherParameters) {
+	return func(params *ft.FuzzyMatcherParameters) {
+		params.MaxEdits[field] = edits
+	}
+}
+
+// withMinDistance returns an Adjust func that scores field with the given min distance
+func withMinDistance(field ft.Field, minDistance float64) func(params *ft.FuzzyMatcherParameters) {
+	return func(params *ft.FuzzyMatcherParameters) {
+		params.MinDistances[field] = minDistance
+	}
+}
+
+func TestFuzzyMatcherCore_RegisterMetric(t *testing.T) {
+	const sameInitial ft.CalculationMethod = "same_initial"
+
+	fuzzyMatcherCore := createMockFuzzyMatcherCore(t, loadTestData(t))
+
+	calls := atomic.Int64{}
+	err := fuzzyMatcherCore.RegisterMetric(sameInitial, ft.SimilarityFunc(func(s1, s2 string) float64 {
+		calls.Add(1)
+		if s1 != "" && s2 != "" && s1[0] == s2[0] {
+			return 0.95
+		}
+		return 0
+	}))
+	require.NoError(t, err)
+
+	t.Run("CalculateSimilarity", func(t *testing.T) {
+		assert.Equal(t, 0.95, fuzzyMatcherCore.CalculateSimilarity("smith", "smyth", sameInitial))
+		assert.Equal(t, 0.0, fuzzyMatcherCore.CalculateSimilarity("smith", "jones", sameInitial))
+	})
+
+	t.Run("FinalScoring", func(t *testing.T) {
+		query := adjustedSource{
+			ExampleSource: fc.ExampleSource{
+				ID:        999,
+				Firstname: "John",
+				Surname:   "Smith",
+				Birthdate: time.Date(1990, 5, 15, 0, 0, 0, 0, time.UTC),
+			},
+			Adjust: withMethod(ft.Surname, sameInitial),
+		}
+
+		found, matches := fuzzyMatcherCore.SearchFuzzy(query)
+		require.True(t, found)
+		assert.Equal(t, 1, matches[0].Entry.ID)
+
+		// firstname 0.2 * 1 + surname 0.4 * 0.95 + birthdate 0.4 * 1
+		assert.InDelta(t, 0.98, matches[0].Score, 0.0001)
+	})
+
+	t.Run("BreadthFirstSearchScoring", func(t *testing.T) {
+		calls.Store(0)
+
+		query := adjustedSource{
+			ExampleSource: fc.ExampleSource{
+				ID:        999,
+				Firstname: "Mich",
+				Surname:   "Brown",
+				Birthdate: time.Date(1992, 8, 22, 0, 0, 0, 0, time.UTC),
+			},
+			Adjust: withMethod(ft.Firstname, sameInitial),
+		}
+
+		found, matches := fuzzyMatcherCore.SearchFuzzy(query)
+		require.True(t, found)
+		assert.Equal(t, 3, matches[0].Entry.ID)
+		assert.Greater(t, calls.Load(), int64(1), "The registered metric should score BFS expansions as well as the final match")
+	})
+
+	t.Run("OverrideBuiltin", func(t *testing.T) {
+		core := &fmc.FuzzyMatcherCore[fc.ExampleSource]{}
+		require.NoError(t, core.RegisterMetric(ft.Levenshtein, ft.SimilarityFunc(func(s1, s2 string) float64 {
+			return 1
+		})))
+
+		assert.Equal(t, 1.0, core.CalculateSimilarity("abc", "xyz", ft.Levenshtein))
+
+		// Other cores keep the built in metric
+		other := &fmc.FuzzyMatcherCore[fc.ExampleSource]{}
+		assert.Equal(t, 0.0, other.CalculateSimilarity("abc", "xyz", ft.Levenshtein))
+	})
+
+	t.Run("InvalidRegistrations", func(t *testing.T) {
+		core := &fmc.FuzzyMatcherCore[fc.ExampleSource]{}
+		assert.Error(t, core.RegisterMetric(ft.Default, ft.SimilarityFunc(func(s1, s2 string) float64 { return 0 })))
+		assert.Error(t, core.RegisterMetric("nil_metric", nil))
+	})
+}
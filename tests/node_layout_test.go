@@ -0,0 +1,175 @@
+package fuzzymatchertests
+
+import (
+	"bytes"
+	"runtime"
+	"testing"
+	"time"
+
+	fc "github.com/oiamo123/fuzzy_matcher/fuzzy_classes"
+	fmc "github.com/oiamo123/fuzzy_matcher/fuzzy_matcher_core"
+	ft "github.com/oiamo123/fuzzy_matcher/fuzzy_types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFuzzyMatcherCore_CompactLayout(t *testing.T) {
+	members := loadTestData(t)
+
+	newCore := func(t *testing.T, layout ft.NodeLayout, members []fc.ExampleSource) *fmc.FuzzyMatcherCore[fc.ExampleSource] {
+		core := &fmc.FuzzyMatcherCore[fc.ExampleSource]{CoreParams: ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{
+			MaxEdits:           6,
+			CorrectOcrMisreads: true,
+			PhoneticIndexes:    map[ft.Field]ft.CalculationMethod{ft.Surname: ft.Metaphone},
+			NodeLayout:         layout,
+		}}
+		require.NoError(t, core.Build(members))
+		return core
+	}
+
+	mapCore := newCore(t, ft.MapLayout, members)
+	compactCore := newCore(t, ft.CompactLayout, members)
+
+	t.Run("SameShape", func(t *testing.T) {
+		assert.Equal(t, trieShape(mapCore.Root), trieShape(compactCore.Root))
+		assert.Less(t, countNodes(compactCore.Root), countNodes(mapCore.Root), "Compressed edges should need fewer nodes")
+		assert.True(t, compactCore.Audit(false).Consistent())
+	})
+
+	t.Run("SameResults", func(t *testing.T) {
+		queries := []fc.ExampleSource{
+			{ID: 999, Firstname: "John", Surname: "Smith", Birthdate: time.Date(1990, 5, 15, 0, 0, 0, 0, time.UTC)},
+			{ID: 999, Firstname: "Jon", Surname: "Smyth", Birthdate: time.Date(1990, 5, 15, 0, 0, 0, 0, time.UTC)},
+			{ID: 999, Firstname: "M1ke", Surname: "Brovvn", Birthdate: time.Date(1992, 8, 22, 0, 0, 0, 0, time.UTC)},
+		}
+
+		for _, query := range queries {
+			expectedFound, expected := mapCore.SearchFuzzy(query)
+			found, matches := compactCore.SearchFuzzy(query)
+			assert.Equal(t, expectedFound, found, query.Firstname)
+			assert.Equal(t, expected, matches, query.Firstname)
+		}
+	})
+
+	t.Run("UpsertAndRemove", func(t *testing.T) {
+		core := newCore(t, ft.CompactLayout, members)
+
+		changed := members[0]
+		changed.Surname = "Smithson"
+		require.NoError(t, core.Upsert([]fc.ExampleSource{changed}))
+		core.RemoveByID(members[1].ID)
+		core.RemoveEntries(members[2:3])
+
+		expected := append([]fc.ExampleSource{changed}, members[3:]...)
+		assert.True(t, core.Audit(false).Consistent())
+		assert.Equal(t, trieShape(newCore(t, ft.MapLayout, expected).Root), trieShape(core.Root))
+		assert.Equal(t, countNodes(newCore(t, ft.CompactLayout, expected).Root), countNodes(core.Root), "Edges left with a single child should be merged again")
+	})
+
+	t.Run("Clean", func(t *testing.T) {
+		expiring := []fc.ExampleSource{
+			{ID: 1, Firstname: "John", Surname: "Smith", Birthdate: time.Date(1990, 5, 15, 0, 0, 0, 0, time.UTC), EventEndUtc: time.Now().Add(-24 * time.Hour)},
+			{ID: 2, Firstname: "Johnny", Surname: "Smith", Birthdate: time.Date(1990, 5, 15, 0, 0, 0, 0, time.UTC), EventEndUtc: time.Now().Add(24 * time.Hour)},
+		}
+
+		core := &fmc.FuzzyMatcherCore[fc.ExampleSource]{CoreParams: ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{
+			MaxEdits:      6,
+			UseExpiration: true,
+			NodeLayout:    ft.CompactLayout,
+		}}
+		require.NoError(t, core.Build(expiring))
+		core.Clean()
+
+		assert.True(t, core.Audit(false).Consistent())
+		assert.NotContains(t, core.Entries, 1)
+
+		fresh := &fmc.FuzzyMatcherCore[fc.ExampleSource]{CoreParams: ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{MaxEdits: 6}}
+		require.NoError(t, fresh.Build(expiring[1:]))
+		assert.Equal(t, trieShape(fresh.Root), trieShape(core.Root))
+	})
+
+	t.Run("SnapshotsAcrossLayouts", func(t *testing.T) {
+		for _, tt := range []struct {
+			name     string
+			from, to *fmc.FuzzyMatcherCore[fc.ExampleSource]
+		}{
+			{"CompactToMap", compactCore, mapCore},
+			{"MapToCompact", mapCore, compactCore},
+		} {
+			t.Run(tt.name, func(t *testing.T) {
+				var snapshot bytes.Buffer
+				require.NoError(t, tt.from.Save(&snapshot))
+
+				restored := &fmc.FuzzyMatcherCore[fc.ExampleSource]{CoreParams: tt.to.CoreParams}
+				require.NoError(t, restored.Load(&snapshot))
+
+				assert.Equal(t, trieShape(tt.to.Root), trieShape(restored.Root))
+				assert.Equal(t, countNodes(tt.to.Root), countNodes(restored.Root))
+				assert.True(t, restored.Audit(false).Consistent())
+			})
+		}
+	})
+}
+
+// syntheticMembers generates n members with common name prefixes and distinct tails, like real names
+func syntheticMembers(n int) []fc.ExampleSource {
+	firstnames := []string{"john", "jonathan", "johanna", "michael", "michelle", "sarah", "sara", "christopher", "christina", "elizabeth"}
+	surnames := []string{"smith", "smithson", "johnson", "johnston", "williams", "williamson", "brown", "browning", "anderson", "andrews"}
+
+	// Scrambles i into a few letters so the tails don't share prefixes the way counting would
+	tail := func(i, seed int) string {
+		letters := []byte{}
+		for v := (i*7919 + seed) % 1_000_003; v > 0; v /= 26 {
+			letters = append(letters, byte('a'+v%26))
+		}
+		return string(letters)
+	}
+
+	members := make([]fc.ExampleSource, 0, n)
+	for i := 0; i < n; i++ {
+		members = append(members, fc.ExampleSource{
+			ID:        i,
+			Firstname: firstnames[i%len(firstnames)] + tail(i, 17),
+			Surname:   surnames[(i/len(firstnames))%len(surnames)] + tail(i, 104_729),
+			Birthdate: time.Date(1950+i%50, time.Month(1+i%12), 1+i%28, 0, 0, 0, 0, time.UTC),
+		})
+	}
+
+	return members
+}
+
+func BenchmarkFuzzyMatcherCore_NodeLayoutMemory(b *testing.B) {
+	members := syntheticMembers(20_000)
+
+	for _, layout := range []ft.NodeLayout{ft.MapLayout, ft.CompactLayout} {
+		name := string(layout)
+		if layout == ft.MapLayout {
+			name = "map"
+		}
+
+		b.Run(name, func(b *testing.B) {
+			var before, after runtime.MemStats
+			var heapBytes int64
+			var nodes int
+
+			for i := 0; i < b.N; i++ {
+				runtime.GC()
+				runtime.ReadMemStats(&before)
+
+				core := &fmc.FuzzyMatcherCore[fc.ExampleSource]{CoreParams: ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{NodeLayout: layout}}
+				require.NoError(b, core.Build(members))
+
+				runtime.GC()
+				runtime.ReadMemStats(&after)
+
+				heapBytes += int64(after.HeapAlloc) - int64(before.HeapAlloc)
+				nodes = countNodes(core.Root)
+				runtime.KeepAlive(core)
+			}
+
+			b.ReportMetric(float64(heapBytes)/float64(b.N), "heap-bytes/op")
+			b.ReportMetric(float64(nodes), "nodes")
+		})
+	}
+}
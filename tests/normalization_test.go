@@ -0,0 +1,118 @@
+package fuzzymatchertests
+
+import (
+	"testing"
+	"time"
+
+	fc "github.com/oiamo123/fuzzy_matcher/fuzzy_classes"
+	fmc "github.com/oiamo123/fuzzy_matcher/fuzzy_matcher_core"
+	ft "github.com/oiamo123/fuzzy_matcher/fuzzy_types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var namePipeline = ft.NormalizationPipeline{
+	Transliterations: fmc.LatinTransliterations,
+	Unicode:          ft.NFKD,
+	FoldDiacritics:   true,
+	FoldCase:         true,
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		pipeline ft.NormalizationPipeline
+		expected string
+	}{
+		{"FoldDiacritics", "José", namePipeline, "jose"},
+		{"FoldUmlaut", "MÜLLER", namePipeline, "muller"},
+		{"Transliterate", "Straße", namePipeline, "strasse"},
+		{"TransliterateDanish", "Søren Kierkegård", namePipeline, "sorenkierkegard"},
+		// й decomposes into и and a breve, so folding diacritics drops the breve
+		{"KeepsCyrillic", "Дмитрий", namePipeline, "дмитрии"},
+		{"KeepsCyrillicWithoutFolding", "Дмитрий", ft.NormalizationPipeline{Unicode: ft.NFC, FoldCase: true}, "дмитрий"},
+		{"KeepsHan", "王 小明", namePipeline, "王小明"},
+		{"CompatibilityForms", "ｆｕｌｌ", namePipeline, "full"},
+		{"KeepDiacritics", "José", ft.NormalizationPipeline{Unicode: ft.NFC, FoldCase: true}, "josé"},
+		{"NoCaseFolding", "José", ft.NormalizationPipeline{FoldDiacritics: true}, "Jose"},
+		{
+			"SpacePolicy",
+			"  O'Brien-Smith   Jr. ",
+			ft.NormalizationPipeline{FoldCase: true, Punctuation: ft.SpaceCharacters, Whitespace: ft.SpaceCharacters},
+			"o brien smith jr",
+		},
+		{
+			"KeepPunctuation",
+			"O'Brien Jr.",
+			ft.NormalizationPipeline{Punctuation: ft.KeepCharacters},
+			"O'BrienJr.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, fmc.Normalize(tt.value, tt.pipeline))
+		})
+	}
+}
+
+func TestFuzzyMatcherCore_NormalizeFieldFor(t *testing.T) {
+	fuzzyMatcherCore := &fmc.FuzzyMatcherCore[fc.ExampleSource]{
+		CoreParams: ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{
+			Normalization: map[ft.Field]ft.NormalizationPipeline{ft.Surname: namePipeline},
+		},
+	}
+
+	assert.Equal(t, "muller", fuzzyMatcherCore.NormalizeFieldFor(ft.Surname, "Müller"))
+	assert.Equal(t, "mller", fuzzyMatcherCore.NormalizeFieldFor(ft.Firstname, "Müller"), "Fields without a pipeline keep the ascii only normalization")
+}
+
+func TestFuzzyMatcherCore_UnicodeSearch(t *testing.T) {
+	members := []fc.ExampleSource{
+		{ID: 1, Firstname: "José", Surname: "Müller", Birthdate: time.Date(1990, 5, 15, 0, 0, 0, 0, time.UTC)},
+		{ID: 2, Firstname: "Дмитрий", Surname: "Иванов", Birthdate: time.Date(1990, 5, 15, 0, 0, 0, 0, time.UTC)},
+		{ID: 3, Firstname: "Алексей", Surname: "Петров", Birthdate: time.Date(1990, 5, 15, 0, 0, 0, 0, time.UTC)},
+	}
+
+	fuzzyMatcherCore := &fmc.FuzzyMatcherCore[fc.ExampleSource]{
+		CoreParams: ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{
+			MaxEdits: 6,
+			Normalization: map[ft.Field]ft.NormalizationPipeline{
+				ft.Firstname: namePipeline,
+				ft.Surname:   namePipeline,
+			},
+		},
+	}
+	require.NoError(t, fuzzyMatcherCore.Build(members))
+
+	search := func(firstname, surname string) []int {
+		_, matches := fuzzyMatcherCore.SearchFuzzy(fc.ExampleSource{
+			ID:        999,
+			Firstname: firstname,
+			Surname:   surname,
+			Birthdate: time.Date(1990, 5, 15, 0, 0, 0, 0, time.UTC),
+		})
+		return matchIDs(matches)
+	}
+
+	t.Run("FoldedLatin", func(t *testing.T) {
+		assert.Equal(t, []int{1}, search("JOSE", "Muller"))
+	})
+
+	t.Run("Cyrillic", func(t *testing.T) {
+		assert.Equal(t, []int{2}, search("Дмитрий", "Иванов"))
+		assert.Equal(t, []int{3}, search("Алексей", "Петров"))
+	})
+
+	t.Run("CyrillicTypo", func(t *testing.T) {
+		assert.Equal(t, []int{2}, search("Дмитри", "Иванов"))
+	})
+
+	t.Run("Remove", func(t *testing.T) {
+		fuzzyMatcherCore.RemoveEntries(members[1:2])
+		assert.Empty(t, search("Дмитрий", "Иванов"))
+		assert.Equal(t, []int{3}, search("Алексей", "Петров"))
+	})
+}
@@ -0,0 +1,98 @@
+package fuzzymatchertests
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	fc "github.com/oiamo123/fuzzy_matcher/fuzzy_classes"
+	fmc "github.com/oiamo123/fuzzy_matcher/fuzzy_matcher_core"
+	ft "github.com/oiamo123/fuzzy_matcher/fuzzy_types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFuzzyMatcherCore_OcrConfusions(t *testing.T) {
+	members := loadWaveMembersTestData(t)
+
+	vendor, err := fmc.LoadOcrConfusionsFile("test_data/ocr_confusions.json")
+	require.NoError(t, err)
+
+	newCore := func(t *testing.T, confusions ft.OcrConfusions, replace bool) *fmc.FuzzyMatcherCore[fc.ExampleSource] {
+		core := &fmc.FuzzyMatcherCore[fc.ExampleSource]{CoreParams: ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{
+			MaxEdits:             6,
+			CorrectOcrMisreads:   true,
+			OcrConfusions:        confusions,
+			ReplaceOcrConfusions: replace,
+		}}
+		require.NoError(t, core.Build(members))
+		return core
+	}
+
+	firstnameEdits := func(t *testing.T, core *fmc.FuzzyMatcherCore[fc.ExampleSource], firstname, surname, birthdate string, expectedID int) []ft.Edit {
+		date, err := time.Parse("2006-01-02", birthdate)
+		require.NoError(t, err)
+
+		found, matches := core.SearchFuzzy(fc.ExampleSource{ID: 999, Firstname: firstname, Surname: surname, Birthdate: date}, ft.SearchOptions{Explain: true})
+		require.True(t, found)
+		assert.Equal(t, expectedID, matches[0].Entry.ID)
+
+		return matches[0].Explanation[ft.Firstname].Edits
+	}
+
+	t.Run("Load", func(t *testing.T) {
+		// Empty sequences are dropped, values are kept as written
+		assert.Equal(t, ft.OcrConfusions{"li": {"h"}, "iii": {"m"}, "ci": {"d"}}, vendor)
+
+		_, err := fmc.LoadOcrConfusions(strings.NewReader(`{"li": "h"}`))
+		assert.Error(t, err)
+	})
+
+	t.Run("ArbitraryLengths", func(t *testing.T) {
+		core := newCore(t, vendor, false)
+
+		assert.Equal(t, []ft.Edit{
+			{Operation: ft.EditOcrMulti, Position: 2, From: "li", To: "h"},
+		}, firstnameEdits(t, core, "Jolin", "Smith", "1990-05-15", 1))
+
+		assert.Equal(t, []ft.Edit{
+			{Operation: ft.EditOcrMulti, Position: 1, From: "iii", To: "m"},
+		}, firstnameEdits(t, core, "Aiiielia", "Rodriguez", "1993-03-16", 16))
+
+		// Values are normalized before they're looked up, so keys have to be lower case
+		assert.Equal(t, []ft.Edit{
+			{Operation: ft.EditOcrMulti, Position: 0, From: "ci", To: "d"},
+		}, firstnameEdits(t, core, "CIaniel", "Garcia", "1988-08-07", 15))
+	})
+
+	t.Run("ExtraConfusions", func(t *testing.T) {
+		// "vv" read for "w" is only corrected once the extra confusions are added
+		edits := firstnameEdits(t, newCore(t, nil, false), "Vvilliam", "Moore", "1987-11-08", 7)
+		for _, edit := range edits {
+			assert.NotEqual(t, ft.EditOcrMulti, edit.Operation)
+		}
+
+		assert.Equal(t, []ft.Edit{
+			{Operation: ft.EditOcrMulti, Position: 0, From: "vv", To: "w"},
+		}, firstnameEdits(t, newCore(t, fmc.ExtraOcrConfusions, false), "Vvilliam", "Moore", "1987-11-08", 7))
+	})
+
+	t.Run("ExtendsBuiltIn", func(t *testing.T) {
+		assert.Equal(t, []ft.Edit{
+			{Operation: ft.EditOcrMulti, Position: 1, From: "rn", To: "m"},
+		}, firstnameEdits(t, newCore(t, vendor, false), "Ernma", "Davis", "1988-03-10", 4))
+	})
+
+	t.Run("ReplacesBuiltIn", func(t *testing.T) {
+		edits := firstnameEdits(t, newCore(t, vendor, true), "Ernma", "Davis", "1988-03-10", 4)
+		for _, edit := range edits {
+			assert.NotEqual(t, ft.EditOcrMulti, edit.Operation, "rn -> m is only in the built in confusions")
+		}
+	})
+
+	t.Run("Merge", func(t *testing.T) {
+		merged := ft.OcrConfusions{"rn": {"m"}}.Merge(ft.OcrConfusions{"rn": {"m", "n"}, "li": {"h"}})
+		assert.Equal(t, ft.OcrConfusions{"rn": {"m", "n"}, "li": {"h"}}, merged)
+	})
+}
@@ -0,0 +1,90 @@
+package fuzzymatchertests
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	fc "github.com/oiamo123/fuzzy_matcher/fuzzy_classes"
+	fmc "github.com/oiamo123/fuzzy_matcher/fuzzy_matcher_core"
+	ft "github.com/oiamo123/fuzzy_matcher/fuzzy_types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrainOcrConfusions(t *testing.T) {
+	pairs := []ft.OcrTrainingPair{
+		{Read: "srnith", Corrected: "smith"},
+		{Read: "barnes", Corrected: "barnes"},
+		{Read: "jolin", Corrected: "john"},
+		{Read: "charlie", Corrected: "charlie"},
+		{Read: "smth", Corrected: "smith"},
+		{Read: "j0hn", Corrected: "john"},
+		{Read: "qwertyu", Corrected: "asdf"},
+	}
+
+	probabilities := fmc.TrainOcrConfusions(pairs, ft.OcrTrainingOptions{})
+
+	t.Run("Probabilities", func(t *testing.T) {
+		assert.Equal(t, ft.OcrProbabilities{
+			{From: "rn", To: "m"}: 0.5, // "rn" in "barnes" was read correctly
+			{From: "li", To: "h"}: 0.5, // "li" in "charlie" was read correctly
+			{From: "m", To: "mi"}: 1,   // The dropped 'i' is anchored to the 'm' before it
+			{From: "0", To: "o"}:  1,
+		}, probabilities, "Differences longer than MaxLength aren't learnt")
+	})
+
+	t.Run("MinCount", func(t *testing.T) {
+		trained := fmc.TrainOcrConfusions(append(pairs, ft.OcrTrainingPair{Read: "barnard", Corrected: "bamard"}), ft.OcrTrainingOptions{MinCount: 2})
+		assert.Equal(t, ft.OcrProbabilities{{From: "rn", To: "m"}: 2.0 / 3}, trained)
+	})
+
+	t.Run("Confusions", func(t *testing.T) {
+		confusions := ft.OcrProbabilities{
+			{From: "0", To: "d"}:  0.2,
+			{From: "0", To: "o"}:  0.7,
+			{From: "rn", To: "m"}: 0.5,
+		}.Confusions()
+		assert.Equal(t, ft.OcrConfusions{"0": {"o", "d"}, "rn": {"m"}}, confusions)
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		data, err := json.Marshal(probabilities)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"rn": {"m": 0.5}, "li": {"h": 0.5}, "m": {"mi": 1}, "0": {"o": 1}}`, string(data))
+
+		var decoded ft.OcrProbabilities
+		require.NoError(t, json.Unmarshal(data, &decoded))
+		assert.Equal(t, probabilities, decoded)
+	})
+
+	t.Run("Search", func(t *testing.T) {
+		members := loadWaveMembersTestData(t)
+		query := fc.ExampleSource{ID: 999, Firstname: "Jolin", Surname: "Smith", Birthdate: time.Date(1990, 5, 15, 0, 0, 0, 0, time.UTC)}
+
+		search := func(t *testing.T, minProbability float64) []ft.Edit {
+			core := &fmc.FuzzyMatcherCore[fc.ExampleSource]{CoreParams: ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{
+				MaxEdits:             6,
+				CorrectOcrMisreads:   true,
+				ReplaceOcrConfusions: true,
+				OcrProbabilities:     probabilities,
+				MinOcrProbability:    minProbability,
+			}}
+			require.NoError(t, core.Build(members))
+
+			found, matches := core.SearchFuzzy(query, ft.SearchOptions{Explain: true})
+			require.True(t, found)
+			assert.Equal(t, 1, matches[0].Entry.ID)
+			return matches[0].Explanation[ft.Firstname].Edits
+		}
+
+		assert.Equal(t, []ft.Edit{
+			{Operation: ft.EditOcrMulti, Position: 2, From: "li", To: "h"},
+		}, search(t, 0))
+
+		for _, edit := range search(t, 0.6) {
+			assert.NotEqual(t, ft.EditOcrMulti, edit.Operation, "li -> h is below the min probability")
+		}
+	})
+}
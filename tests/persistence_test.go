@@ -0,0 +1,148 @@
+package fuzzymatchertests
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	fc "github.com/oiamo123/fuzzy_matcher/fuzzy_classes"
+	fmc "github.com/oiamo123/fuzzy_matcher/fuzzy_matcher_core"
+	ft "github.com/oiamo123/fuzzy_matcher/fuzzy_types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFuzzyMatcherCore_SaveAndLoad(t *testing.T) {
+	members := loadTestData(t)
+	params := ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{
+		MaxEdits:      6,
+		UseExpiration: true,
+	}
+
+	original := &fmc.FuzzyMatcherCore[fc.ExampleSource]{CoreParams: params}
+	require.NoError(t, original.Build(members))
+
+	var snapshot bytes.Buffer
+	require.NoError(t, original.Save(&snapshot))
+
+	restored := &fmc.FuzzyMatcherCore[fc.ExampleSource]{CoreParams: params}
+	require.NoError(t, restored.Load(bytes.NewReader(snapshot.Bytes())))
+
+	t.Run("StateRestored", func(t *testing.T) {
+		assert.Equal(t, original.Entries, restored.Entries)
+		assert.Equal(t, original.ExpiryHeap.Len(), restored.ExpiryHeap.Len())
+		assert.True(t, original.ExpiryHeap[0].Expiry.Equal(restored.ExpiryHeap[0].Expiry))
+		assert.Equal(t, countNodes(original.Root), countNodes(restored.Root))
+	})
+
+	t.Run("SearchResultsMatch", func(t *testing.T) {
+		queries := []fc.ExampleSource{
+			{ID: 999, Firstname: "John", Surname: "Smith", Birthdate: time.Date(1990, 5, 15, 0, 0, 0, 0, time.UTC)},
+			{ID: 999, Firstname: "Jon", Surname: "Smith", Birthdate: time.Date(1990, 5, 15, 0, 0, 0, 0, time.UTC)},
+			{ID: 999, Firstname: "Mike", Surname: "Brown", Birthdate: time.Date(1992, 8, 22, 0, 0, 0, 0, time.UTC)},
+		}
+
+		for _, query := range queries {
+			expectedFound, expected := original.SearchFuzzy(query)
+			found, matches := restored.SearchFuzzy(query)
+			assert.Equal(t, expectedFound, found)
+			assert.Equal(t, expected, matches)
+		}
+	})
+
+	t.Run("Deterministic", func(t *testing.T) {
+		var again bytes.Buffer
+		require.NoError(t, restored.Save(&again))
+		assert.Equal(t, snapshot.Bytes(), again.Bytes())
+	})
+
+	t.Run("ExpiryStillApplies", func(t *testing.T) {
+		expiring := &fmc.FuzzyMatcherCore[fc.ExampleSource]{CoreParams: params}
+		require.NoError(t, expiring.Build([]fc.ExampleSource{{
+			ID:          1,
+			Firstname:   "John",
+			Surname:     "Smith",
+			Birthdate:   time.Date(1990, 5, 15, 0, 0, 0, 0, time.UTC),
+			EventEndUtc: time.Now().Add(-24 * time.Hour),
+		}}))
+
+		var buf bytes.Buffer
+		require.NoError(t, expiring.Save(&buf))
+
+		loaded := &fmc.FuzzyMatcherCore[fc.ExampleSource]{CoreParams: params}
+		require.NoError(t, loaded.Load(&buf))
+
+		found, _ := loaded.SearchFuzzy(fc.ExampleSource{
+			ID:        999,
+			Firstname: "John",
+			Surname:   "Smith",
+			Birthdate: time.Date(1990, 5, 15, 0, 0, 0, 0, time.UTC),
+		})
+		assert.False(t, found, "Expired entries should be cleaned after loading")
+	})
+}
+
+func TestFuzzyMatcherCore_LoadErrors(t *testing.T) {
+	core := &fmc.FuzzyMatcherCore[fc.ExampleSource]{
+		CoreParams: ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{MaxEdits: 6},
+	}
+	require.NoError(t, core.Build(loadTestData(t)))
+
+	var snapshot bytes.Buffer
+	require.NoError(t, core.Save(&snapshot))
+	valid := snapshot.Bytes()
+
+	corrupt := func(index int) []byte {
+		data := append([]byte{}, valid...)
+		data[index] ^= 0xFF
+		return data
+	}
+
+	tests := []struct {
+		name     string
+		data     []byte
+		expected error
+	}{
+		{"BadMagic", corrupt(0), fmc.ErrSnapshotFormat},
+		{"BadVersion", corrupt(5), fmc.ErrSnapshotVersion},
+		{"BadChecksum", corrupt(len(valid) - 1), fmc.ErrSnapshotChecksum},
+		{"Truncated", valid[:len(valid)/2], fmc.ErrSnapshotFormat},
+		{"Empty", nil, fmc.ErrSnapshotFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := &fmc.FuzzyMatcherCore[fc.ExampleSource]{}
+			err := target.Load(bytes.NewReader(tt.data))
+			assert.ErrorIs(t, err, tt.expected)
+			assert.Nil(t, target.Root, "A failed load should leave the core untouched")
+		})
+	}
+
+	t.Run("BadParameters", func(t *testing.T) {
+		params := []ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{
+			{SearchEngine: "bogus"},
+			{PhoneticIndexes: map[ft.Field]ft.CalculationMethod{ft.Surname: ft.JaroWinkler}},
+			{FieldBackends: map[ft.Field]ft.BackendKind{ft.Surname: "bogus"}},
+		}
+
+		for _, p := range params {
+			target := &fmc.FuzzyMatcherCore[fc.ExampleSource]{CoreParams: p}
+			assert.Error(t, target.Load(bytes.NewReader(valid)))
+			assert.Nil(t, target.Root, "A failed load should leave the core untouched")
+		}
+	})
+}
+
+func countNodes(node *ft.FuzzyMatcherNode) int {
+	if node == nil {
+		return 0
+	}
+
+	count := 1
+	node.EachEdge(func(child *ft.FuzzyMatcherNode) {
+		count += countNodes(child)
+	})
+	return count
+}
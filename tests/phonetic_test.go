@@ -0,0 +1,128 @@
+package fuzzymatchertests
+
+import (
+	"testing"
+	"time"
+
+	fc "github.com/oiamo123/fuzzy_matcher/fuzzy_classes"
+	fmc "github.com/oiamo123/fuzzy_matcher/fuzzy_matcher_core"
+	ft "github.com/oiamo123/fuzzy_matcher/fuzzy_types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// exactOnly turns off edits for every field so only exact or phonetic lookups can match
+func exactOnly(params *ft.FuzzyMatcherParameters) {
+	for field := range params.MaxEdits {
+		params.MaxEdits[field] = 0
+		params.MaxDepth[field] = 0
+	}
+}
+
+func TestFuzzyMatcherCore_PhoneticSimilarity(t *testing.T) {
+	fuzzyMatcherCore := &fmc.FuzzyMatcherCore[fc.ExampleSource]{}
+
+	tests := []struct {
+		name     string
+		s1, s2   string
+		method   ft.CalculationMethod
+		expected float64
+	}{
+		{"Soundex_SameCode", "smyth", "smith", ft.Soundex, 1},
+		{"Soundex_DifferentFirstLetter", "kathryn", "catherine", ft.Soundex, 0.75},
+		{"Metaphone_SameCode", "fillips", "phillips", ft.Metaphone, 1},
+		{"Metaphone_SecondaryCode", "kathryn", "catherine", ft.Metaphone, 1},
+		{"NYSIIS_SameCode", "rodrigues", "rodriguez", ft.NYSIIS, 1},
+		{"Identical", "lee", "lee", ft.NYSIIS, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.expected, fuzzyMatcherCore.CalculateSimilarity(tt.s1, tt.s2, tt.method), 0.0001)
+		})
+	}
+}
+
+func TestFuzzyMatcherCore_PhoneticIndex(t *testing.T) {
+	members := []fc.ExampleSource{
+		{ID: 1, Firstname: "Catherine", Surname: "Phillips", Birthdate: time.Date(1990, 5, 15, 0, 0, 0, 0, time.UTC)},
+		{ID: 2, Firstname: "John", Surname: "Smith", Birthdate: time.Date(1990, 5, 15, 0, 0, 0, 0, time.UTC)},
+	}
+
+	newCore := func(t *testing.T, indexes map[ft.Field]ft.CalculationMethod) *fmc.FuzzyMatcherCore[fc.ExampleSource] {
+		core := &fmc.FuzzyMatcherCore[fc.ExampleSource]{
+			CoreParams: ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{
+				MaxEdits:        6,
+				PhoneticIndexes: indexes,
+			},
+		}
+		require.NoError(t, core.Build(members))
+		return core
+	}
+
+	query := adjustedSource{
+		ExampleSource: fc.ExampleSource{
+			ID:        999,
+			Firstname: "Kathryn",
+			Surname:   "Fillips",
+			Birthdate: time.Date(1990, 5, 15, 0, 0, 0, 0, time.UTC),
+		},
+		Adjust: func(params *ft.FuzzyMatcherParameters) {
+			exactOnly(params)
+			params.CalculationMethods[ft.Firstname] = ft.Metaphone
+			params.CalculationMethods[ft.Surname] = ft.Metaphone
+		},
+	}
+
+	t.Run("WithoutIndex", func(t *testing.T) {
+		found, _ := newCore(t, nil).SearchFuzzy(query)
+		assert.False(t, found, "Exact spelling search shouldn't find a phonetic match")
+	})
+
+	t.Run("WithIndex", func(t *testing.T) {
+		core := newCore(t, map[ft.Field]ft.CalculationMethod{
+			ft.Firstname: ft.Metaphone,
+			ft.Surname:   ft.Metaphone,
+		})
+
+		found, matches := core.SearchFuzzy(query)
+		require.True(t, found)
+		require.Len(t, matches, 1)
+		assert.Equal(t, 1, matches[0].Entry.ID)
+		assert.InDelta(t, 1.0, matches[0].Score, 0.0001)
+	})
+
+	t.Run("SpellingStillMatches", func(t *testing.T) {
+		core := newCore(t, map[ft.Field]ft.CalculationMethod{ft.Surname: ft.Soundex})
+
+		found, matches := core.SearchFuzzy(fc.ExampleSource{
+			ID:        999,
+			Firstname: "John",
+			Surname:   "Smith",
+			Birthdate: time.Date(1990, 5, 15, 0, 0, 0, 0, time.UTC),
+		})
+		require.True(t, found)
+		assert.Equal(t, 2, matches[0].Entry.ID)
+	})
+
+	t.Run("RemoveEntriesClearsPhoneticIndex", func(t *testing.T) {
+		core := newCore(t, map[ft.Field]ft.CalculationMethod{
+			ft.Firstname: ft.Metaphone,
+			ft.Surname:   ft.Metaphone,
+		})
+		core.RemoveEntries(members[:1])
+
+		found, _ := core.SearchFuzzy(query)
+		assert.False(t, found)
+	})
+
+	t.Run("InvalidMethod", func(t *testing.T) {
+		core := &fmc.FuzzyMatcherCore[fc.ExampleSource]{
+			CoreParams: ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{
+				PhoneticIndexes: map[ft.Field]ft.CalculationMethod{ft.Surname: ft.JaroWinkler},
+			},
+		}
+		assert.Error(t, core.Build(members))
+	})
+}
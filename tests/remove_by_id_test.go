@@ -0,0 +1,151 @@
+package fuzzymatchertests
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	fc "github.com/oiamo123/fuzzy_matcher/fuzzy_classes"
+	fmc "github.com/oiamo123/fuzzy_matcher/fuzzy_matcher_core"
+	ft "github.com/oiamo123/fuzzy_matcher/fuzzy_types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFuzzyMatcherCore_RemoveByID(t *testing.T) {
+	members := loadTestData(t)
+	params := ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{
+		MaxEdits:        6,
+		PhoneticIndexes: map[ft.Field]ft.CalculationMethod{ft.Surname: ft.Metaphone},
+	}
+
+	newCore := func(t *testing.T, members []fc.ExampleSource) *fmc.FuzzyMatcherCore[fc.ExampleSource] {
+		core := &fmc.FuzzyMatcherCore[fc.ExampleSource]{CoreParams: params}
+		require.NoError(t, core.Build(members))
+		return core
+	}
+
+	query := func(member fc.ExampleSource) adjustedSource {
+		member.ID = 999
+		return adjustedSource{ExampleSource: member, Adjust: exactOnly}
+	}
+
+	t.Run("RemovesEveryPath", func(t *testing.T) {
+		core := newCore(t, members)
+		core.RemoveByID(members[0].ID, members[1].ID)
+
+		for _, removed := range members[:2] {
+			found, _ := core.SearchFuzzy(query(removed))
+			assert.False(t, found)
+			assert.NotContains(t, core.Entries, removed.ID)
+		}
+
+		found, matches := core.SearchFuzzy(query(members[2]))
+		require.True(t, found)
+		assert.Equal(t, members[2].ID, matches[0].Entry.ID)
+
+		assert.Equal(t, trieShape(newCore(t, members[2:]).Root), trieShape(core.Root), "Counts and pruning should match a trie built without the entries")
+	})
+
+	t.Run("AfterUpsert", func(t *testing.T) {
+		core := newCore(t, members)
+
+		// The record changed upstream, only its ID is known when it's removed
+		changed := members[0]
+		changed.Surname = "Changed"
+		require.NoError(t, core.Upsert([]fc.ExampleSource{changed}))
+		core.RemoveByID(changed.ID)
+
+		assert.Equal(t, trieShape(newCore(t, members[1:]).Root), trieShape(core.Root))
+	})
+
+	t.Run("AfterLoad", func(t *testing.T) {
+		var snapshot bytes.Buffer
+		require.NoError(t, newCore(t, members).Save(&snapshot))
+
+		core := &fmc.FuzzyMatcherCore[fc.ExampleSource]{CoreParams: params}
+		require.NoError(t, core.Load(&snapshot))
+		core.RemoveByID(members[0].ID)
+
+		assert.Equal(t, trieShape(newCore(t, members[1:]).Root), trieShape(core.Root))
+	})
+
+	t.Run("DropsExpiries", func(t *testing.T) {
+		expiring := []fc.ExampleSource{
+			{ID: 1, Firstname: "John", Surname: "Smith", Birthdate: time.Date(1990, 5, 15, 0, 0, 0, 0, time.UTC), EventEndUtc: time.Now().Add(time.Hour)},
+			{ID: 2, Firstname: "Sarah", Surname: "Smith", Birthdate: time.Date(1985, 12, 3, 0, 0, 0, 0, time.UTC), EventEndUtc: time.Now().Add(time.Hour)},
+		}
+
+		core := &fmc.FuzzyMatcherCore[fc.ExampleSource]{CoreParams: ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{MaxEdits: 6, UseExpiration: true}}
+		require.NoError(t, core.Build(expiring))
+		core.RemoveByID(1)
+
+		require.Equal(t, 3, core.ExpiryHeap.Len())
+		for _, expiry := range core.ExpiryHeap {
+			assert.Equal(t, 2, expiry.ID)
+		}
+	})
+
+	t.Run("SkipsStaleExpiries", func(t *testing.T) {
+		// Every entry expired an hour ago, but nothing has been cleaned yet
+		expired := []fc.ExampleSource{}
+		for _, member := range members[:4] {
+			member.EventEndUtc = time.Now().Add(-13 * time.Hour)
+			expired = append(expired, member)
+		}
+
+		core := &fmc.FuzzyMatcherCore[fc.ExampleSource]{CoreParams: ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{MaxEdits: 6, UseExpiration: true}}
+		require.NoError(t, core.Build(expired))
+		core.RemoveByID(1)
+
+		// Removal leaves the expiries in the heap until they make up half of it
+		assert.Equal(t, 12, core.ExpiryHeap.Len())
+
+		renewed := members[0]
+		renewed.EventEndUtc = time.Now().Add(time.Hour)
+		require.NoError(t, core.Upsert([]fc.ExampleSource{renewed}))
+		core.Clean()
+
+		// The expiries from before the removal don't expire the renewed entry
+		assert.Equal(t, map[int]fc.ExampleSource{1: renewed}, core.Entries)
+		assert.Equal(t, 3, core.ExpiryHeap.Len())
+		assert.True(t, core.Audit(false).Consistent())
+	})
+
+	t.Run("RemoveEntriesDropsExpiries", func(t *testing.T) {
+		// John's expiry has passed but hasn't been cleaned, Sarah shares his surname
+		expiring := []fc.ExampleSource{
+			{ID: 1, Firstname: "John", Surname: "Smith", Birthdate: time.Date(1990, 5, 15, 0, 0, 0, 0, time.UTC), EventEndUtc: time.Now().Add(-13 * time.Hour)},
+			{ID: 2, Firstname: "Sarah", Surname: "Smith", Birthdate: time.Date(1985, 12, 3, 0, 0, 0, 0, time.UTC), EventEndUtc: time.Now().Add(time.Hour)},
+		}
+
+		core := &fmc.FuzzyMatcherCore[fc.ExampleSource]{CoreParams: ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{MaxEdits: 6, UseExpiration: true}}
+		require.NoError(t, core.Build(expiring))
+		core.RemoveEntries(expiring[:1])
+
+		require.Equal(t, 3, core.ExpiryHeap.Len())
+		for _, expiry := range core.ExpiryHeap {
+			assert.Equal(t, 2, expiry.ID)
+		}
+
+		// Inserted again with the same record, so only a stale expiry could remove it
+		renewed := expiring[0]
+		renewed.EventEndUtc = time.Now().Add(time.Hour)
+		require.NoError(t, core.Build([]fc.ExampleSource{renewed}))
+		core.Clean()
+
+		ids := core.Root
+		for _, char := range "surname:smith" {
+			ids = ids.Child(char)
+		}
+		assert.ElementsMatch(t, []int{1, 2}, ids.IDs())
+	})
+
+	t.Run("UnknownID", func(t *testing.T) {
+		core := newCore(t, members)
+		core.RemoveByID(-1)
+
+		assert.Equal(t, trieShape(newCore(t, members).Root), trieShape(core.Root))
+	})
+}
@@ -0,0 +1,105 @@
+package fuzzymatchertests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	fc "github.com/oiamo123/fuzzy_matcher/fuzzy_classes"
+	fmc "github.com/oiamo123/fuzzy_matcher/fuzzy_matcher_core"
+	ft "github.com/oiamo123/fuzzy_matcher/fuzzy_types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFuzzyMatcherCore_SearchContext(t *testing.T) {
+	members := loadTestData(t)
+
+	query := fc.ExampleSource{
+		ID:        999,
+		Firstname: members[0].Firstname + "x",
+		Surname:   members[0].Surname,
+		Birthdate: members[0].Birthdate,
+	}
+
+	// Searching with every limit raised makes Recurse and BFS visit most of the trie
+	pathological := adjustedSource{
+		ExampleSource: query,
+		Adjust: func(params *ft.FuzzyMatcherParameters) {
+			for field := range params.MaxEdits {
+				params.MaxEdits[field] = 8
+				params.MaxDepth[field] = 8
+			}
+		},
+	}
+
+	t.Run("MatchesSearchFuzzy", func(t *testing.T) {
+		fuzzyMatcherCore := createMockFuzzyMatcherCore(t, members)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+
+		expectedFound, expected := fuzzyMatcherCore.SearchFuzzy(query)
+		found, matches, err := fuzzyMatcherCore.SearchContext(ctx, query)
+		require.NoError(t, err)
+		assert.Equal(t, expectedFound, found)
+		assert.Equal(t, matchIDs(expected), matchIDs(matches))
+	})
+
+	t.Run("AlreadyCancelled", func(t *testing.T) {
+		fuzzyMatcherCore := createMockFuzzyMatcherCore(t, members)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		found, matches, err := fuzzyMatcherCore.SearchContext(ctx, query)
+		assert.False(t, found)
+		assert.Empty(t, matches)
+		assert.ErrorIs(t, err, fmc.ErrSearchTruncated)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+
+	t.Run("CancelledDuringTraversal", func(t *testing.T) {
+		const cancelling ft.CalculationMethod = "cancelling"
+
+		fuzzyMatcherCore := createMockFuzzyMatcherCore(t, members)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		// BFS scores each expansion with the field's metric, so the search is cancelled mid traversal
+		require.NoError(t, fuzzyMatcherCore.RegisterMetric(cancelling, ft.SimilarityFunc(func(s1, s2 string) float64 {
+			cancel()
+			return 1
+		})))
+
+		withCancellingMetric := pathological
+		withCancellingMetric.Adjust = func(params *ft.FuzzyMatcherParameters) {
+			pathological.Adjust(params)
+			params.CalculationMethods[ft.Firstname] = cancelling
+		}
+
+		_, expected := createMockFuzzyMatcherCore(t, members).SearchFuzzy(pathological, ft.SearchOptions{Limit: -1})
+
+		_, matches, err := fuzzyMatcherCore.SearchContext(ctx, withCancellingMetric, ft.SearchOptions{Limit: -1})
+		assert.ErrorIs(t, err, fmc.ErrSearchTruncated)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Subset(t, matchIDs(expected), matchIDs(matches), "Partial results should come from the full result set")
+	})
+
+	t.Run("NodeBudget", func(t *testing.T) {
+		fuzzyMatcherCore := createMockFuzzyMatcherCore(t, members)
+
+		_, _, err := fuzzyMatcherCore.SearchContext(context.Background(), pathological, ft.SearchOptions{MaxNodes: 50})
+		assert.ErrorIs(t, err, fmc.ErrSearchTruncated)
+		assert.ErrorIs(t, err, fmc.ErrNodeBudgetExceeded)
+		assert.False(t, errors.Is(err, context.Canceled))
+
+		expectedFound, expected := fuzzyMatcherCore.SearchFuzzy(pathological)
+		found, matches, err := fuzzyMatcherCore.SearchContext(context.Background(), pathological, ft.SearchOptions{MaxNodes: 1_000_000})
+		require.NoError(t, err, "A budget that isn't spent shouldn't truncate the search")
+		assert.Equal(t, expectedFound, found)
+		assert.Equal(t, matchIDs(expected), matchIDs(matches))
+	})
+}
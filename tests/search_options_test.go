@@ -0,0 +1,117 @@
+package fuzzymatchertests
+
+import (
+	"testing"
+	"time"
+
+	fc "github.com/oiamo123/fuzzy_matcher/fuzzy_classes"
+	fmc "github.com/oiamo123/fuzzy_matcher/fuzzy_matcher_core"
+	ft "github.com/oiamo123/fuzzy_matcher/fuzzy_types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createDuplicateMembers creates n identical John Smiths plus a single Jon Smith with the last ID
+func createDuplicateMembers(n int) []fc.ExampleSource {
+	members := make([]fc.ExampleSource, 0, n+1)
+	for i := 1; i <= n; i++ {
+		members = append(members, fc.ExampleSource{
+			ID:        i,
+			Firstname: "John",
+			Surname:   "Smith",
+			Birthdate: time.Date(1990, 5, 15, 0, 0, 0, 0, time.UTC),
+		})
+	}
+
+	members = append(members, fc.ExampleSource{
+		ID:        n + 1,
+		Firstname: "Jon",
+		Surname:   "Smith",
+		Birthdate: time.Date(1990, 5, 15, 0, 0, 0, 0, time.UTC),
+	})
+
+	return members
+}
+
+func createSearchOptionsCore(t *testing.T, members []fc.ExampleSource) *fmc.FuzzyMatcherCore[fc.ExampleSource] {
+	fuzzyMatcherCore := &fmc.FuzzyMatcherCore[fc.ExampleSource]{
+		CoreParams: ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{
+			MaxEdits: 6,
+		},
+	}
+
+	require.NoError(t, fuzzyMatcherCore.Build(members))
+	return fuzzyMatcherCore
+}
+
+func matchIDs(matches []ft.FuzzyMatch[fc.ExampleSource]) []int {
+	ids := make([]int, 0, len(matches))
+	for _, match := range matches {
+		ids = append(ids, match.Entry.ID)
+	}
+	return ids
+}
+
+func TestSearchOptions(t *testing.T) {
+	fuzzyMatcherCore := createSearchOptionsCore(t, createDuplicateMembers(8))
+
+	query := fc.ExampleSource{
+		ID:        999,
+		Firstname: "John",
+		Surname:   "Smith",
+		Birthdate: time.Date(1990, 5, 15, 0, 0, 0, 0, time.UTC),
+	}
+
+	t.Run("DefaultLimit", func(t *testing.T) {
+		found, matches := fuzzyMatcherCore.SearchFuzzy(query)
+		assert.True(t, found)
+		assert.Equal(t, []int{1, 2, 3, 4, 5}, matchIDs(matches), "Ties should default to lowest ID first")
+	})
+
+	t.Run("NoLimit", func(t *testing.T) {
+		found, matches := fuzzyMatcherCore.SearchFuzzy(query, ft.SearchOptions{Limit: -1})
+		assert.True(t, found)
+		require.Len(t, matches, 9)
+		assert.Equal(t, 9, matches[8].Entry.ID, "The fuzzy match should be ranked last")
+		assert.Less(t, matches[8].Score, matches[7].Score)
+	})
+
+	t.Run("Pagination", func(t *testing.T) {
+		seen := []int{}
+		for offset := 0; ; offset += 4 {
+			found, matches := fuzzyMatcherCore.SearchFuzzy(query, ft.SearchOptions{Limit: 4, Offset: offset})
+			if !found {
+				assert.Empty(t, matches)
+				break
+			}
+			assert.LessOrEqual(t, len(matches), 4)
+			seen = append(seen, matchIDs(matches)...)
+		}
+
+		assert.Equal(t, []int{1, 2, 3, 4, 5, 6, 7, 8, 9}, seen, "Paging should visit every match exactly once")
+	})
+
+	t.Run("MinScore", func(t *testing.T) {
+		found, matches := fuzzyMatcherCore.SearchFuzzy(query, ft.SearchOptions{Limit: -1, MinScore: 0.999})
+		assert.True(t, found)
+		assert.Len(t, matches, 8)
+		for _, match := range matches {
+			assert.GreaterOrEqual(t, match.Score, 0.999)
+		}
+
+		found, matches = fuzzyMatcherCore.SearchFuzzy(query, ft.SearchOptions{MinScore: 1.1})
+		assert.False(t, found)
+		assert.Empty(t, matches)
+	})
+
+	t.Run("TieBreakIDDesc", func(t *testing.T) {
+		_, matches := fuzzyMatcherCore.SearchFuzzy(query, ft.SearchOptions{Limit: 3, TieBreak: ft.TieBreakIDDesc})
+		assert.Equal(t, []int{8, 7, 6}, matchIDs(matches))
+	})
+
+	t.Run("TieBreakEdits", func(t *testing.T) {
+		_, matches := fuzzyMatcherCore.SearchFuzzy(query, ft.SearchOptions{Limit: -1, TieBreak: ft.TieBreakEdits})
+		assert.Equal(t, []int{1, 2, 3, 4, 5, 6, 7, 8, 9}, matchIDs(matches))
+	})
+}
@@ -0,0 +1,84 @@
+package fuzzymatchertests
+
+import (
+	"testing"
+	"time"
+
+	fc "github.com/oiamo123/fuzzy_matcher/fuzzy_classes"
+	fmc "github.com/oiamo123/fuzzy_matcher/fuzzy_matcher_core"
+	ft "github.com/oiamo123/fuzzy_matcher/fuzzy_types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFuzzyMatcherCore_Stats(t *testing.T) {
+	members := []fc.ExampleSource{
+		{ID: 1, Firstname: "John", Surname: "Smith", Birthdate: time.Date(1990, 5, 15, 0, 0, 0, 0, time.UTC), EventEndUtc: time.Now().Add(24 * time.Hour)},
+		{ID: 2, Firstname: "Johnny", Surname: "Smith", Birthdate: time.Date(1990, 5, 15, 0, 0, 0, 0, time.UTC), EventEndUtc: time.Now().Add(24 * time.Hour)},
+	}
+
+	newCore := func(t *testing.T, layout ft.NodeLayout) *fmc.FuzzyMatcherCore[fc.ExampleSource] {
+		core := &fmc.FuzzyMatcherCore[fc.ExampleSource]{CoreParams: ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{
+			MaxEdits:      6,
+			UseExpiration: true,
+			NodeLayout:    layout,
+		}}
+		require.NoError(t, core.Build(members))
+		return core
+	}
+
+	t.Run("Empty", func(t *testing.T) {
+		stats := (&fmc.FuzzyMatcherCore[fc.ExampleSource]{}).Stats()
+		assert.Zero(t, stats.Nodes)
+		assert.Zero(t, stats.EstimatedBytes)
+	})
+
+	t.Run("MapLayout", func(t *testing.T) {
+		stats := newCore(t, ft.MapLayout).Stats()
+
+		// "birthdate:", "firstname:" and "surname:" are shared by every value
+		assert.Equal(t, 28, stats.KeyNodes)
+		assert.Equal(t, map[string]int{"birthdate": 8, "firstname": 6, "surname": 5}, stats.NodesByKey)
+		assert.Equal(t, 1+28+8+6+5, stats.Nodes)
+
+		// birthdate:19900515, firstname:john, firstname:johnny and surname:smith
+		assert.Equal(t, 4, stats.EndOfStringNodes)
+		assert.Equal(t, 6, stats.IDs)
+		assert.Equal(t, 2, stats.Entries)
+		assert.Equal(t, 8, stats.MaxDepth)
+		assert.InDelta(t, float64(8+4+6+5)/4, stats.AverageDepth, 1e-9)
+
+		assert.Equal(t, map[int]int{0: 3, 1: stats.Nodes - 4, 3: 1}, stats.BranchingHistogram)
+		assert.Equal(t, 6, stats.PendingExpiries)
+		assert.Positive(t, stats.EstimatedBytes)
+	})
+
+	t.Run("CompactLayout", func(t *testing.T) {
+		mapStats := newCore(t, ft.MapLayout).Stats()
+		stats := newCore(t, ft.CompactLayout).Stats()
+
+		// One edge per field, with "john" split from "ny"
+		assert.Equal(t, 5, stats.Nodes)
+		assert.Zero(t, stats.KeyNodes)
+		assert.Equal(t, map[string]int{"birthdate": 1, "firstname": 2, "surname": 1}, stats.NodesByKey)
+		assert.Equal(t, map[int]int{0: 3, 1: 1, 3: 1}, stats.BranchingHistogram)
+
+		assert.Equal(t, mapStats.EndOfStringNodes, stats.EndOfStringNodes)
+		assert.Equal(t, mapStats.IDs, stats.IDs)
+		assert.Equal(t, mapStats.MaxDepth, stats.MaxDepth)
+		assert.Equal(t, mapStats.AverageDepth, stats.AverageDepth)
+		assert.Less(t, stats.EstimatedBytes, mapStats.EstimatedBytes)
+	})
+
+	t.Run("TracksRemovals", func(t *testing.T) {
+		core := newCore(t, ft.MapLayout)
+		core.RemoveByID(2)
+
+		stats := core.Stats()
+		assert.Equal(t, 1, stats.Entries)
+		assert.Equal(t, 3, stats.IDs)
+		assert.Equal(t, map[string]int{"birthdate": 8, "firstname": 4, "surname": 5}, stats.NodesByKey)
+		assert.Equal(t, 3, stats.PendingExpiries, "Removed IDs drop their expiries")
+	})
+}
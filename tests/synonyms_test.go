@@ -0,0 +1,128 @@
+package fuzzymatchertests
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	fc "github.com/oiamo123/fuzzy_matcher/fuzzy_classes"
+	fmc "github.com/oiamo123/fuzzy_matcher/fuzzy_matcher_core"
+	ft "github.com/oiamo123/fuzzy_matcher/fuzzy_types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loadNicknameDictionary(t *testing.T) ft.SynonymDictionary {
+	dictionary, err := fmc.LoadSynonymDictionaryFile("test_data/nickname_dictionary.json")
+	require.NoError(t, err, "Failed to load nickname dictionary")
+	return dictionary
+}
+
+func createSynonymCore(t *testing.T, dictionary ft.SynonymDictionary, score float64) *fmc.FuzzyMatcherCore[fc.ExampleSource] {
+	fuzzyMatcherCore := &fmc.FuzzyMatcherCore[fc.ExampleSource]{
+		CoreParams: ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{
+			MaxEdits:     6,
+			Synonyms:     map[ft.Field]ft.SynonymDictionary{ft.Firstname: dictionary},
+			SynonymScore: score,
+		},
+	}
+
+	require.NoError(t, fuzzyMatcherCore.Build(loadWaveMembersTestData(t)))
+	return fuzzyMatcherCore
+}
+
+func TestLoadSynonymDictionary(t *testing.T) {
+	dictionary := loadNicknameDictionary(t)
+
+	assert.Equal(t, []string{"benjamin", "benedict"}, dictionary["ben"])
+	assert.Equal(t, []string{"william"}, dictionary["will"], "Values should be trimmed and lowercased, empty variants dropped")
+
+	_, err := fmc.LoadSynonymDictionary(strings.NewReader(`["not", "a", "dictionary"]`))
+	assert.Error(t, err)
+
+	_, err = fmc.LoadSynonymDictionaryFile("test_data/does_not_exist.json")
+	assert.Error(t, err)
+}
+
+func TestSynonymDictionary_WithReverse(t *testing.T) {
+	dictionary := ft.SynonymDictionary{"bill": {"william"}, "will": {"william"}}.WithReverse()
+
+	assert.Equal(t, []string{"william"}, dictionary["bill"])
+	assert.ElementsMatch(t, []string{"bill", "will"}, dictionary["william"])
+}
+
+// Runs nickname_tests.json with exact matching only, so matches can only come from the dictionary
+func TestFuzzyMatcherCore_FuzzySearch_NicknameDictionary(t *testing.T) {
+	fuzzyMatcherCore := createSynonymCore(t, loadNicknameDictionary(t), 0)
+
+	for _, testCase := range loadNicknameTestData(t).TestCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			birthdate, err := time.Parse("2006-01-02", testCase.Query.Birthdate)
+			require.NoError(t, err)
+
+			query := adjustedSource{
+				ExampleSource: fc.ExampleSource{
+					ID:        999,
+					Firstname: testCase.Query.Firstname,
+					Surname:   testCase.Query.Surname,
+					Birthdate: birthdate,
+				},
+				Adjust: exactOnly,
+			}
+
+			found, matches := fuzzyMatcherCore.SearchFuzzy(query)
+			assert.Equal(t, testCase.Expected.ShouldFind, found)
+
+			for _, expectedMatch := range testCase.Expected.ExpectedMatches {
+				require.NotEmpty(t, matches)
+				assert.Equal(t, expectedMatch.MemberID, matches[0].Entry.ID)
+				assert.GreaterOrEqual(t, matches[0].Score, expectedMatch.MinScore)
+				assert.LessOrEqual(t, matches[0].Score, expectedMatch.MaxScore)
+			}
+		})
+	}
+}
+
+func TestFuzzyMatcherCore_SynonymScoring(t *testing.T) {
+	query := fc.ExampleSource{
+		ID:        999,
+		Firstname: "Bill",
+		Surname:   "Moore",
+		Birthdate: time.Date(1987, 11, 8, 0, 0, 0, 0, time.UTC),
+	}
+
+	t.Run("DefaultScore", func(t *testing.T) {
+		fuzzyMatcherCore := createSynonymCore(t, loadNicknameDictionary(t), 0)
+
+		found, matches := fuzzyMatcherCore.SearchFuzzy(query)
+		require.True(t, found)
+		assert.Equal(t, 7, matches[0].Entry.ID)
+		assert.Equal(t, map[ft.Field]string{ft.Firstname: "william"}, matches[0].Synonyms)
+
+		// firstname 0.2 * 0.9 + surname 0.4 + birthdate 0.4
+		assert.InDelta(t, 0.98, matches[0].Score, 0.0001)
+	})
+
+	t.Run("ConfiguredScore", func(t *testing.T) {
+		fuzzyMatcherCore := createSynonymCore(t, loadNicknameDictionary(t), 0.75)
+
+		found, matches := fuzzyMatcherCore.SearchFuzzy(query)
+		require.True(t, found)
+		assert.InDelta(t, 0.95, matches[0].Score, 0.0001)
+	})
+
+	t.Run("DirectMatchNotMarked", func(t *testing.T) {
+		fuzzyMatcherCore := createSynonymCore(t, loadNicknameDictionary(t), 0)
+
+		found, matches := fuzzyMatcherCore.SearchFuzzy(fc.ExampleSource{
+			ID:        999,
+			Firstname: "William",
+			Surname:   "Moore",
+			Birthdate: time.Date(1987, 11, 8, 0, 0, 0, 0, time.UTC),
+		})
+		require.True(t, found)
+		assert.Nil(t, matches[0].Synonyms)
+		assert.InDelta(t, 1.0, matches[0].Score, 0.0001)
+	})
+}
@@ -0,0 +1,146 @@
+package fuzzymatchertests
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	fc "github.com/oiamo123/fuzzy_matcher/fuzzy_classes"
+	fmc "github.com/oiamo123/fuzzy_matcher/fuzzy_matcher_core"
+	ft "github.com/oiamo123/fuzzy_matcher/fuzzy_types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func loadTranspositionTestCases(t *testing.T) FuzzySearchTestData {
+	data, err := os.ReadFile("test_data/transposition_cases.json")
+	require.NoError(t, err, "Failed to read transposition test cases")
+
+	var testData FuzzySearchTestData
+	err = json.Unmarshal(data, &testData)
+	require.NoError(t, err, "Failed to unmarshal transposition test cases")
+
+	return testData
+}
+
+func TestFuzzyMatcherCore_Transposition(t *testing.T) {
+	testData := loadTranspositionTestCases(t)
+	members := loadWaveMembersTestData(t)
+
+	fuzzyMatcherCore := &fmc.FuzzyMatcherCore[fc.ExampleSource]{
+		CoreParams: ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{MaxEdits: 6},
+	}
+	require.NoError(t, fuzzyMatcherCore.Build(members))
+
+	for _, testCase := range testData.TestCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			birthdate, err := time.Parse("2006-01-02", testCase.Query.Birthdate)
+			require.NoError(t, err, "Failed to parse birthdate for test case %s", testCase.Name)
+
+			query := fc.ExampleSource{
+				ID:        999,
+				Firstname: testCase.Query.Firstname,
+				Surname:   testCase.Query.Surname,
+				Birthdate: birthdate,
+			}
+
+			found, matches := fuzzyMatcherCore.SearchFuzzy(query, ft.SearchOptions{Explain: true})
+			assert.Equal(t, testCase.Expected.ShouldFind, found, testCase.Description)
+
+			if testCase.Expected.MinMatches > 0 {
+				assert.GreaterOrEqual(t, len(matches), testCase.Expected.MinMatches)
+			} else {
+				assert.Empty(t, matches)
+			}
+
+			for _, expectedMatch := range testCase.Expected.ExpectedMatches {
+				require.NotEmpty(t, matches)
+				match := matches[0]
+				assert.Equal(t, expectedMatch.MemberID, match.Entry.ID)
+				assert.GreaterOrEqual(t, match.Score, expectedMatch.MinScore)
+				assert.LessOrEqual(t, match.Score, expectedMatch.MaxScore)
+
+				// Each swap should cost a single edit, extensions past the end of the query are free
+				for field, explanation := range match.Explanation {
+					paid := 0
+					for _, edit := range explanation.Edits {
+						if edit.Operation != ft.EditExtension {
+							paid++
+						}
+					}
+					assert.LessOrEqual(t, paid, 1, "%s: %+v", field, explanation.Edits)
+				}
+			}
+
+			for i, match := range matches {
+				t.Logf("  Match %d: ID=%d, Name=%s %s, Score=%.6f",
+					i, match.Entry.ID, match.Entry.Firstname, match.Entry.Surname, match.Score)
+			}
+		})
+	}
+}
+
+func TestFuzzyMatcherCore_TranspositionSingleEdit(t *testing.T) {
+	members := loadWaveMembersTestData(t)
+
+	fuzzyMatcherCore := &fmc.FuzzyMatcherCore[fc.ExampleSource]{
+		CoreParams: ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{MaxEdits: 1},
+	}
+	require.NoError(t, fuzzyMatcherCore.Build(members))
+
+	tests := []struct {
+		name       string
+		firstname  string
+		surname    string
+		birthdate  string
+		field      ft.Field
+		expectedID int
+	}{
+		{"JhonSmith", "Jhon", "Smith", "1990-05-15", ft.Firstname, 1},
+		{"JohnSmtih", "John", "Smtih", "1990-05-15", ft.Surname, 1},
+	}
+
+	// A single swap is the whole budget, so it has to be tried when the next character isn't in the trie
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			birthdate, err := time.Parse("2006-01-02", tt.birthdate)
+			require.NoError(t, err)
+
+			found, matches := fuzzyMatcherCore.SearchFuzzy(adjustedSource{
+				ExampleSource: fc.ExampleSource{ID: 999, Firstname: tt.firstname, Surname: tt.surname, Birthdate: birthdate},
+				Adjust:        withMaxEdits(tt.field, 1),
+			})
+			require.True(t, found)
+			assert.Equal(t, tt.expectedID, matches[0].Entry.ID)
+		})
+	}
+}
+
+func TestFuzzyMatcherCore_CalculateSimilarity_DamerauLevenshtein(t *testing.T) {
+	fuzzyMatcherCore := &fmc.FuzzyMatcherCore[fc.ExampleSource]{}
+
+	tests := []struct {
+		name     string
+		s1, s2   string
+		expected float64
+	}{
+		{"Identical", "john", "john", 1},
+		{"Transposition", "jhon", "john", 0.75},
+		{"Substitution", "jahn", "john", 0.75},
+		{"Empty", "", "", 1},
+		{"Unicode", "jösé", "jöés", 0.75},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.InDelta(t, tt.expected, fuzzyMatcherCore.CalculateSimilarity(tt.s1, tt.s2, ft.DamerauLevenshtein), 1e-9)
+		})
+	}
+
+	assert.Less(t,
+		fuzzyMatcherCore.CalculateSimilarity("jhon", "john", ft.Levenshtein),
+		fuzzyMatcherCore.CalculateSimilarity("jhon", "john", ft.DamerauLevenshtein),
+		"Levenshtein counts a swap as two edits")
+}
@@ -0,0 +1,166 @@
+package fuzzymatchertests
+
+import (
+	"sort"
+	"testing"
+	"time"
+
+	fc "github.com/oiamo123/fuzzy_matcher/fuzzy_classes"
+	fmc "github.com/oiamo123/fuzzy_matcher/fuzzy_matcher_core"
+	ft "github.com/oiamo123/fuzzy_matcher/fuzzy_types"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// trieNodeState is what a trie node holds, keyed by its path in trieShape
+type trieNodeState struct {
+	Count         int
+	IDs           []int
+	IsEndofString bool
+}
+
+// trieShape flattens a trie into its paths so two tries can be compared
+// Compressed edges are walked a rune at a time, so tries using either node layout flatten the same way
+func trieShape(root *ft.FuzzyMatcherNode) map[string]trieNodeState {
+	shape := map[string]trieNodeState{}
+
+	var walk func(node *ft.FuzzyMatcherNode, path string)
+	walk = func(node *ft.FuzzyMatcherNode, path string) {
+		ids := node.IDs()
+		sort.Ints(ids)
+
+		shape[path] = trieNodeState{Count: node.Count, IDs: ids, IsEndofString: node.IsEndofString}
+		node.EachChild(func(child *ft.FuzzyMatcherNode) bool {
+			walk(child, path+string(child.Char))
+			return true
+		})
+	}
+
+	if root != nil {
+		walk(root, "")
+	}
+
+	return shape
+}
+
+// expirySource is an ExampleSource that can be left without an expiry
+type expirySource struct {
+	fc.ExampleSource
+	NoExpiry bool
+}
+
+func (s expirySource) CreateFuzzyEntry() *ft.FuzzyEntry {
+	entry := s.ExampleSource.CreateFuzzyEntry()
+	if s.NoExpiry {
+		entry.Expiry = time.Time{}
+	}
+	return entry
+}
+
+func TestFuzzyMatcherCore_Upsert(t *testing.T) {
+	original := []fc.ExampleSource{
+		{ID: 1, Firstname: "John", Surname: "Smith", Birthdate: time.Date(1990, 5, 15, 0, 0, 0, 0, time.UTC)},
+		{ID: 2, Firstname: "Sarah", Surname: "Smith", Birthdate: time.Date(1985, 12, 3, 0, 0, 0, 0, time.UTC)},
+	}
+
+	updated := []fc.ExampleSource{
+		{ID: 1, Firstname: "John", Surname: "Smyth", Birthdate: time.Date(1990, 5, 15, 0, 0, 0, 0, time.UTC)},
+		{ID: 2, Firstname: "Sarah", Surname: "Smith", Birthdate: time.Date(1985, 12, 3, 0, 0, 0, 0, time.UTC)},
+	}
+
+	newCore := func(t *testing.T, params ft.FuzzyMatcherCoreParameters[fc.ExampleSource], members []fc.ExampleSource) *fmc.FuzzyMatcherCore[fc.ExampleSource] {
+		params.MaxEdits = 6
+		core := &fmc.FuzzyMatcherCore[fc.ExampleSource]{CoreParams: params}
+		require.NoError(t, core.Build(members))
+		return core
+	}
+
+	exact := func(member fc.ExampleSource) adjustedSource {
+		member.ID = 999
+		return adjustedSource{ExampleSource: member, Adjust: exactOnly}
+	}
+
+	t.Run("ReplacesChangedFields", func(t *testing.T) {
+		core := newCore(t, ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{}, original)
+		require.NoError(t, core.Upsert(updated[:1]))
+
+		found, _ := core.SearchFuzzy(exact(original[0]))
+		assert.False(t, found, "The old surname should no longer point at the entry")
+
+		found, matches := core.SearchFuzzy(exact(updated[0]))
+		require.True(t, found)
+		assert.Equal(t, updated[0], matches[0].Entry)
+		assert.Equal(t, updated[0], core.Entries[1])
+
+		found, matches = core.SearchFuzzy(exact(original[1]))
+		require.True(t, found, "Shared paths must keep the other entry")
+		assert.Equal(t, 2, matches[0].Entry.ID)
+	})
+
+	t.Run("CountsMatchAFreshBuild", func(t *testing.T) {
+		for name, params := range map[string]ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{
+			"Spelling": {},
+			"Phonetic": {PhoneticIndexes: map[ft.Field]ft.CalculationMethod{ft.Surname: ft.Soundex}},
+		} {
+			t.Run(name, func(t *testing.T) {
+				core := newCore(t, params, original)
+				require.NoError(t, core.Upsert(updated))
+				require.NoError(t, core.Upsert(updated), "Upserting the same entries again shouldn't change anything")
+
+				assert.Equal(t, trieShape(newCore(t, params, updated).Root), trieShape(core.Root))
+			})
+		}
+	})
+
+	t.Run("InsertsNewIDs", func(t *testing.T) {
+		core := &fmc.FuzzyMatcherCore[fc.ExampleSource]{CoreParams: ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{MaxEdits: 6}}
+		require.NoError(t, core.Upsert(original))
+
+		assert.Equal(t, trieShape(newCore(t, ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{}, original).Root), trieShape(core.Root))
+	})
+
+	t.Run("ReschedulesExpiry", func(t *testing.T) {
+		expired := original[0]
+		expired.EventEndUtc = time.Now().Add(-24 * time.Hour)
+
+		renewed := updated[0]
+		renewed.EventEndUtc = time.Now().Add(24 * time.Hour)
+
+		core := newCore(t, ft.FuzzyMatcherCoreParameters[fc.ExampleSource]{UseExpiration: true}, []fc.ExampleSource{expired})
+		require.NoError(t, core.Upsert([]fc.ExampleSource{renewed}))
+
+		// One expiry per field, none left over from the expired entry
+		require.Equal(t, 3, core.ExpiryHeap.Len())
+		for _, expiry := range core.ExpiryHeap {
+			assert.Equal(t, renewed.CreateFuzzyEntry().Expiry, expiry.Expiry)
+		}
+
+		found, matches := core.SearchFuzzy(exact(renewed))
+		require.True(t, found, "The renewed entry shouldn't be cleaned up with its old expiry")
+		assert.Equal(t, 1, matches[0].Entry.ID)
+	})
+	t.Run("RejectedBatchChangesNothing", func(t *testing.T) {
+		expiring := []expirySource{}
+		for _, member := range original {
+			member.EventEndUtc = time.Now().Add(time.Hour)
+			expiring = append(expiring, expirySource{ExampleSource: member})
+		}
+
+		renewed := expirySource{ExampleSource: updated[0]}
+		renewed.EventEndUtc = time.Now().Add(24 * time.Hour)
+
+		core := &fmc.FuzzyMatcherCore[expirySource]{CoreParams: ft.FuzzyMatcherCoreParameters[expirySource]{MaxEdits: 6, UseExpiration: true}}
+		require.NoError(t, core.Build(expiring))
+		before := trieShape(core.Root)
+
+		// The second entry has no expiry, so the renewal before it mustn't be applied either
+		require.Error(t, core.Upsert([]expirySource{renewed, {ExampleSource: updated[1], NoExpiry: true}}))
+
+		assert.Equal(t, expiring[0], core.Entries[1])
+		assert.Equal(t, before, trieShape(core.Root))
+		for _, expiry := range core.ExpiryHeap {
+			assert.Equal(t, expiring[expiry.ID-1].CreateFuzzyEntry().Expiry, expiry.Expiry)
+		}
+	})
+}